@@ -0,0 +1,135 @@
+package mykonf
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// LoadContext carries the state of a single Loader.Load call.
+type LoadContext struct {
+	Loader *Loader
+	// Koanf holds the keys merged so far.
+	Koanf *koanf.Koanf
+	// Conf is the pointer passed to Loader.Load.
+	Conf any
+
+	envToKey map[string]string
+	files    []string
+	// origins holds the sources that set each flat key, in load order.
+	origins map[string][]Origin
+	fields  *fieldKeys
+	envVars []envVar
+	dec     *decrypter
+	slices  []sliceField
+	maps    []mapField
+}
+
+// envVar is a prefixed env var seen by an env source.
+type envVar struct {
+	kind   string
+	prefix string
+	name   string
+	key    string
+}
+
+func newLoadContext(l *Loader, conf any) *LoadContext {
+	return &LoadContext{
+		Loader:  l,
+		Koanf:   koanf.New(l.delim),
+		Conf:    conf,
+		origins: make(map[string][]Origin),
+	}
+}
+
+// Load merges the keys read by p and pa on top of the keys loaded so
+// far. It is how custom sources should load, so that the keys are known
+// to come from name.
+func (c *LoadContext) Load(name string, p koanf.Provider, pa koanf.Parser) error {
+	return c.load(Origin{Kind: OriginProvider, Name: name}, p, pa)
+}
+
+func (c *LoadContext) load(o Origin, p koanf.Provider, pa koanf.Parser) error {
+	k := koanf.New(c.Loader.delim)
+	err := k.Load(p, pa)
+	if err != nil {
+		return err
+	}
+	return c.merge(k, func(string) Origin { return o })
+}
+
+// merge merges k on top of the keys loaded so far, recording the origin
+// of each of its keys.
+func (c *LoadContext) merge(k *koanf.Koanf, origin func(key string) Origin) error {
+	for key, v := range k.All() {
+		c.record(key, origin(key), v)
+	}
+	return c.Koanf.Merge(k)
+}
+
+func (c *LoadContext) record(key string, o Origin, v any) {
+	o.Value = v
+	c.origins[key] = append(c.origins[key], o)
+
+	if _, ok := v.([]any); ok {
+		// the items of the slice replaced are gone
+		for k := range c.origins {
+			if strings.HasPrefix(k, key+"[") {
+				delete(c.origins, k)
+			}
+		}
+	}
+}
+
+// origin returns the source that last set key.
+func (c *LoadContext) origin(key string) Origin {
+	chain := c.origins[key]
+	if len(chain) == 0 {
+		return Origin{}
+	}
+	return chain[len(chain)-1]
+}
+
+// mapProvider is a koanf.Provider over an already parsed map.
+type mapProvider map[string]any
+
+func (p mapProvider) ReadBytes() ([]byte, error) {
+	return nil, errors.New("map provider does not support this method")
+}
+
+func (p mapProvider) Read() (map[string]any, error) { return p, nil }
+
+// AddFile records a file or directory the config was read from, so that
+// Watch reloads when it changes. Missing files may be added too.
+func (c *LoadContext) AddFile(path string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	c.files = append(c.files, abs)
+}
+
+// Files returns the files and directories recorded with AddFile.
+func (c *LoadContext) Files() []string { return c.files }
+
+// EnvToKey returns the env name to key path mapping of Conf.
+func (c *LoadContext) EnvToKey() map[string]string {
+	if c.envToKey == nil {
+		c.envToKey = envToKey(c.Conf, c.Loader.tag, c.Loader.delim)
+	}
+	return c.envToKey
+}
+
+// envKey maps an env name without prefix to a key path, walking into the
+// values of maps of structs. Unknown names are lowercased.
+func (c *LoadContext) envKey(name string) string {
+	if key, ok := c.EnvToKey()[name]; ok {
+		return key
+	}
+	if key, ok := c.mapEnv(name); ok {
+		return key
+	}
+	return strings.ToLower(name)
+}
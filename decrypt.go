@@ -0,0 +1,384 @@
+package mykonf
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// ageKeyFileEnv, after the prefix, is the path of the age identity
+	// file used to decrypt config files.
+	ageKeyFileEnv = "AGE_KEY_FILE"
+	// sopsKey holds the metadata of SOPS files.
+	sopsKey = "sops"
+	// agePrefix starts values encrypted with EncryptValue.
+	agePrefix = "ENC[age,"
+)
+
+// sopsValue matches a value encrypted by SOPS.
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.+),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// sopsMacOnlyEncrypted starts the MAC of SOPS files with
+// mac_only_encrypted set, as SOPS does.
+var sopsMacOnlyEncrypted = []byte{0x8a, 0x3f, 0xd2, 0xad, 0x54, 0xce, 0x66, 0x52, 0x7b, 0x10, 0x34, 0xf3, 0xd1, 0x47, 0xbe, 0xb, 0xb, 0x97, 0x5b, 0x3b, 0xf4, 0x4f, 0x72, 0xc6, 0xfd, 0xad, 0xec, 0x81, 0x76, 0xf2, 0x7d, 0x69}
+
+// EncryptValue encrypts plaintext to recipients, returning an
+// ENC[age,...] value to put in a config file.
+func EncryptValue(plaintext string, recipients ...age.Recipient) (string, error) {
+	var b bytes.Buffer
+	w, err := age.Encrypt(&b, recipients...)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.WriteString(w, plaintext)
+	if err != nil {
+		return "", err
+	}
+	err = w.Close()
+	if err != nil {
+		return "", err
+	}
+	return agePrefix + base64.StdEncoding.EncodeToString(b.Bytes()) + "]", nil
+}
+
+// decrypter decrypts SOPS files with age recipients and ENC[age,...]
+// values, fully offline.
+type decrypter struct {
+	// identities loads the age identities, called once when the first
+	// encrypted value is found.
+	identities func() ([]age.Identity, error)
+
+	loaded bool
+	ids    []age.Identity
+	err    error
+}
+
+func (d *decrypter) load() ([]age.Identity, error) {
+	if !d.loaded {
+		d.ids, d.err = d.identities()
+		d.loaded = true
+	}
+	return d.ids, d.err
+}
+
+// decryptFile decrypts m, a parsed config file, in place. The sops key
+// of SOPS files is removed, once their MAC is checked against doc, the
+// yaml or json document m was parsed from.
+func (d *decrypter) decryptFile(m map[string]any, doc *yaml.Node, delim string) error {
+	var dataKey []byte
+	if meta, ok := m[sopsKey].(map[string]any); ok {
+		delete(m, sopsKey)
+		var err error
+		dataKey, err = d.dataKey(meta)
+		if err != nil {
+			return err
+		}
+		err = checkSopsMac(meta, doc, dataKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	d.decryptValues(m, nil, dataKey, delim, &errs)
+	return errors.Join(errs...)
+}
+
+// decryptValues decrypts the strings in v, the value at path, and
+// returns it. Maps and slices are decrypted in place.
+func (d *decrypter) decryptValues(v any, path []string, dataKey []byte, delim string, errs *[]error) any {
+	switch v := v.(type) {
+	case string:
+		var pv any
+		var err error
+		switch {
+		case sopsValue.MatchString(v):
+			if dataKey == nil {
+				err = errors.New("SOPS value outside of a SOPS file")
+				break
+			}
+			// SOPS authenticates values with their path
+			pv, err = decryptSops(v, dataKey, strings.Join(path, ":")+":")
+		case strings.HasPrefix(v, agePrefix):
+			pv, err = d.decryptAge(v)
+		default:
+			return v
+		}
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", strings.Join(path, delim), err))
+			return v
+		}
+		return protected{pv}
+
+	case map[string]any:
+		for k, sub := range v {
+			v[k] = d.decryptValues(sub, append(path[:len(path):len(path)], k), dataKey, delim, errs)
+		}
+
+	case []any:
+		// items share the path of the slice
+		for i, sub := range v {
+			v[i] = d.decryptValues(sub, path, dataKey, delim, errs)
+		}
+	}
+	return v
+}
+
+// dataKey decrypts the data key of a SOPS file with its age recipients.
+func (d *decrypter) dataKey(meta map[string]any) ([]byte, error) {
+	if _, ok := meta["key_groups"]; ok {
+		return nil, errors.New("sops: key groups are not supported")
+	}
+	recipients, _ := meta["age"].([]any)
+	if len(recipients) == 0 {
+		return nil, errors.New("sops: no age recipients, other key types need a key service")
+	}
+
+	ids, err := d.load()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, r := range recipients {
+		r, _ := r.(map[string]any)
+		enc, _ := r["enc"].(string)
+		names = append(names, fmt.Sprint(r["recipient"]))
+
+		pr, err := age.Decrypt(armor.NewReader(strings.NewReader(enc)), ids...)
+		if err != nil {
+			continue
+		}
+		return io.ReadAll(pr)
+	}
+	return nil, fmt.Errorf("sops: no identity matches the age recipients %s", strings.Join(names, ", "))
+}
+
+func (d *decrypter) decryptAge(v string) (string, error) {
+	ids, err := d.load()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(v, agePrefix), "]"))
+	if err != nil {
+		return "", fmt.Errorf("malformed age value: %w", err)
+	}
+	r, err := age.Decrypt(bytes.NewReader(b), ids...)
+	if err != nil {
+		return "", err
+	}
+	b, err = io.ReadAll(r)
+	return string(b), err
+}
+
+// checkSopsMac fails unless the mac of a SOPS file matches the values of
+// doc, so that values cannot be removed, replaced or swapped for
+// plaintext.
+func checkSopsMac(meta map[string]any, doc *yaml.Node, dataKey []byte) error {
+	mac, _ := meta["mac"].(string)
+	if mac == "" {
+		return errors.New("sops: no MAC")
+	}
+	// the MAC is authenticated with lastmodified as formatted by SOPS
+	var lastModified time.Time
+	switch v := meta["lastmodified"].(type) {
+	case time.Time:
+		lastModified = v
+	case string:
+		var err error
+		lastModified, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("sops: lastmodified: %w", err)
+		}
+	default:
+		return errors.New("sops: no lastmodified")
+	}
+	want, err := decryptSops(mac, dataKey, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("sops: MAC: %w", err)
+	}
+
+	if doc == nil {
+		return errors.New("sops: cannot check the MAC without the document")
+	}
+	h := sha512.New()
+	onlyEncrypted, _ := meta["mac_only_encrypted"].(bool)
+	if onlyEncrypted {
+		h.Write(sopsMacOnlyEncrypted)
+	}
+	err = sopsHash(h, doc, nil, dataKey, onlyEncrypted)
+	if err != nil {
+		return err
+	}
+	if fmt.Sprintf("%X", h.Sum(nil)) != want {
+		return errors.New("sops: MAC mismatch, the file was modified after it was encrypted")
+	}
+	return nil
+}
+
+// sopsHash writes the plaintext values of n, at path, to h in document
+// order, like SOPS computes the MAC. The top-level sops key is skipped.
+func sopsHash(h hash.Hash, n *yaml.Node, path []string, dataKey []byte, onlyEncrypted bool) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			err := sopsHash(h, c, path, dataKey, onlyEncrypted)
+			if err != nil {
+				return err
+			}
+		}
+
+	case yaml.AliasNode:
+		return sopsHash(h, n.Alias, path, dataKey, onlyEncrypted)
+
+	case yaml.SequenceNode:
+		// items share the path of the slice
+		for _, c := range n.Content {
+			err := sopsHash(h, c, path, dataKey, onlyEncrypted)
+			if err != nil {
+				return err
+			}
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i].Value
+			if path == nil && k == sopsKey {
+				continue
+			}
+			err := sopsHash(h, n.Content[i+1], append(path[:len(path):len(path)], k), dataKey, onlyEncrypted)
+			if err != nil {
+				return err
+			}
+		}
+
+	case yaml.ScalarNode:
+		var v any
+		err := n.Decode(&v)
+		if err != nil || v == nil {
+			return err
+		}
+		s, encrypted := v.(string)
+		encrypted = encrypted && sopsValue.MatchString(s)
+		if encrypted {
+			v, err = decryptSops(s, dataKey, strings.Join(path, ":")+":")
+			if err != nil {
+				return fmt.Errorf("%s: %w", strings.Join(path, ":"), err)
+			}
+		}
+		if onlyEncrypted && !encrypted {
+			return nil
+		}
+		b, err := sopsBytes(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", strings.Join(path, ":"), err)
+		}
+		h.Write(b)
+	}
+	return nil
+}
+
+// sopsBytes returns the bytes SOPS hashes for v.
+func sopsBytes(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case int:
+		return []byte(strconv.Itoa(v)), nil
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case bool:
+		if v {
+			return []byte("True"), nil
+		}
+		return []byte("False"), nil
+	case time.Time:
+		return v.MarshalText()
+	}
+	return nil, fmt.Errorf("sops: cannot hash %T", v)
+}
+
+// decryptSops decrypts a SOPS value, whose additional data is its path.
+func decryptSops(v string, key []byte, aad string) (any, error) {
+	match := sopsValue.FindStringSubmatch(v)
+	if match == nil {
+		return nil, errors.New("malformed SOPS value")
+	}
+	var parts [3][]byte
+	for i := range parts {
+		var err error
+		parts[i], err = base64.StdEncoding.DecodeString(match[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed SOPS value: %w", err)
+		}
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, err
+	}
+	b, err := gcm.Open(nil, iv, append(data, tag...), []byte(aad))
+	if err != nil {
+		return nil, errors.New("sops: value does not match the data key or its path")
+	}
+
+	switch typ := match[4]; typ {
+	case "str", "bytes", "time":
+		return string(b), nil
+	case "int":
+		return strconv.Atoi(string(b))
+	case "float":
+		return strconv.ParseFloat(string(b), 64)
+	case "bool":
+		return strconv.ParseBool(string(b))
+	default:
+		return nil, fmt.Errorf("sops: unsupported type %s", typ)
+	}
+}
+
+// decrypter returns the decrypter of config files, reading the identity
+// file from {prefix}AGE_KEY_FILE.
+func (c *LoadContext) decrypter() *decrypter {
+	if c.dec == nil {
+		name := c.Loader.envPrefix + ageKeyFileEnv
+		c.dec = &decrypter{identities: func() ([]age.Identity, error) {
+			path := os.Getenv(name)
+			if path == "" {
+				return nil, fmt.Errorf("encrypted values need %s", name)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			defer f.Close()
+			ids, err := age.ParseIdentities(f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			return ids, nil
+		}}
+	}
+	return c.dec
+}
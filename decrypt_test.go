@@ -0,0 +1,207 @@
+package mykonf
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+)
+
+type decryptConfig struct {
+	Name     string `yaml:"name"`
+	Database struct {
+		Password Secret  `yaml:"password"`
+		Port     int     `yaml:"port"`
+		Ratio    float64 `yaml:"ratio"`
+		Enabled  bool    `yaml:"enabled"`
+	} `yaml:"database"`
+	Tokens []string `yaml:"tokens"`
+	Debug  bool     `yaml:"debug_unencrypted"`
+}
+
+// ageKeyFile writes a new age identity to dir and returns it with the
+// path of the file.
+func ageKeyFile(t *testing.T, dir string) (*age.X25519Identity, string) {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("failed to generate identity: %v", err)
+	}
+	path := filepath.Join(dir, "key.txt")
+	if err := os.WriteFile(path, []byte(id.String()+"\n"), 0600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+	return id, path
+}
+
+// sopsEncrypt encrypts v the way SOPS does.
+func TestLoader_DecryptSops(t *testing.T) {
+	// produced by sops 3.13.3 from the same plaintext, see testdata/sops
+	for _, name := range []string{"config.yaml", "config.json"} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("DECRYPT_AGE_KEY_FILE", filepath.Join("testdata", "sops", "key.txt"))
+
+			var conf decryptConfig
+			err := New("DECRYPT_",
+				WithSources(FileSource(filepath.Join("testdata", "sops", name)), EnvSource("DECRYPT_")),
+				WithStrict(true),
+				WithUnusedEnv(UnusedEnvError),
+			).Load(&conf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if conf.Name != "app" {
+				t.Errorf("expected Name=app, got %q", conf.Name)
+			}
+			if conf.Database.Password.Reveal() != "hunter2" {
+				t.Errorf("expected Database.Password=hunter2, got %q", conf.Database.Password.Reveal())
+			}
+			if conf.Database.Port != 5432 || conf.Database.Ratio != 0.5 || !conf.Database.Enabled {
+				t.Errorf("expected Database={5432 0.5 true}, got %+v", conf.Database)
+			}
+			if len(conf.Tokens) != 2 || conf.Tokens[0] != "t1" || conf.Tokens[1] != "t2" {
+				t.Errorf("expected Tokens=[t1 t2], got %v", conf.Tokens)
+			}
+			if !conf.Debug {
+				t.Error("expected Debug=true")
+			}
+		})
+	}
+}
+
+func TestLoader_DecryptSopsTampered(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "sops", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(b), "\n")
+	line := func(prefix string) int {
+		for i, l := range lines {
+			if strings.HasPrefix(strings.TrimSpace(l), prefix) {
+				return i
+			}
+		}
+		t.Fatalf("no line starting with %q", prefix)
+		return -1
+	}
+	edit := func(fn func(lines []string) []string) string {
+		return strings.Join(fn(slices.Clone(lines)), "\n")
+	}
+
+	tests := map[string]string{
+		"plaintext": edit(func(l []string) []string {
+			l[line("password:")] = "    password: hunter3"
+			return l
+		}),
+		"removed": edit(func(l []string) []string {
+			return slices.Delete(l, line("ratio:"), line("ratio:")+1)
+		}),
+		"reordered": edit(func(l []string) []string {
+			i := line("- ENC")
+			l[i], l[i+1] = l[i+1], l[i]
+			return l
+		}),
+		"unencrypted": edit(func(l []string) []string {
+			l[line("debug_unencrypted:")] = "debug_unencrypted: false"
+			return l
+		}),
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			writeFiles(t, tmpDir, map[string]string{"config.yaml": content})
+			t.Setenv("DECRYPT_AGE_KEY_FILE", filepath.Join("testdata", "sops", "key.txt"))
+
+			var conf decryptConfig
+			err := New("DECRYPT_", WithSources(FileSource(filepath.Join(tmpDir, "config.yaml")))).Load(&conf)
+			if err == nil || !strings.Contains(err.Error(), "sops: MAC mismatch") {
+				t.Errorf("expected MAC mismatch error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLoader_DecryptSopsMalformedMac(t *testing.T) {
+	b, err := os.ReadFile(filepath.Join("testdata", "sops", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := regexp.MustCompile(`(?m)^(\s*mac:).*$`).ReplaceAllString(string(b), "$1 garbage")
+
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{"config.yaml": content})
+	t.Setenv("DECRYPT_AGE_KEY_FILE", filepath.Join("testdata", "sops", "key.txt"))
+
+	var conf decryptConfig
+	err = New("DECRYPT_", WithSources(FileSource(filepath.Join(tmpDir, "config.yaml")))).Load(&conf)
+	if err == nil || !strings.Contains(err.Error(), "sops: MAC: malformed SOPS value") {
+		t.Errorf("expected malformed MAC error, got %v", err)
+	}
+}
+
+func TestLoader_DecryptAgeValue(t *testing.T) {
+	tmpDir := t.TempDir()
+	id, keyFile := ageKeyFile(t, tmpDir)
+
+	enc, err := EncryptValue("hunter2", id.Recipient())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeFiles(t, tmpDir, map[string]string{
+		"config.json": `{"name": "app", "database": {"password": "` + enc + `"}}`,
+	})
+	path := filepath.Join(tmpDir, "config.json")
+
+	var conf decryptConfig
+	loader := New("DECRYPT_AGE_", WithSources(FileSource(path)))
+
+	err = loader.Load(&conf)
+	if err == nil || !strings.Contains(err.Error(), "encrypted values need DECRYPT_AGE_AGE_KEY_FILE") {
+		t.Errorf("expected missing key file error, got %v", err)
+	}
+
+	t.Setenv("DECRYPT_AGE_AGE_KEY_FILE", keyFile)
+	err = loader.Load(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conf.Database.Password.Reveal() != "hunter2" {
+		t.Errorf("expected Database.Password=hunter2, got %q", conf.Database.Password.Reveal())
+	}
+	if conf.Name != "app" {
+		t.Errorf("expected Name=app, got %q", conf.Name)
+	}
+}
+
+func TestLoader_DecryptWrongIdentity(t *testing.T) {
+	tmpDir := t.TempDir()
+	_, keyFile := ageKeyFile(t, tmpDir)
+
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := EncryptValue("hunter2", other.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "database:\n  password: " + enc + "\n",
+	})
+
+	t.Setenv("DECRYPT_WRONG_AGE_KEY_FILE", keyFile)
+
+	var conf decryptConfig
+	err = New("DECRYPT_WRONG_", WithSources(FileSource(filepath.Join(tmpDir, "config.yaml")))).Load(&conf)
+	if err == nil || !strings.Contains(err.Error(), "database.password: identity did not match any of the recipients") {
+		t.Errorf("expected no matching identity error, got %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("expected error not to contain the secret, got %v", err)
+	}
+}
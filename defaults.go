@@ -0,0 +1,334 @@
+package mykonf
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/creasty/defaults"
+	kmaps "github.com/knadh/koanf/maps"
+	"github.com/knadh/koanf/v2"
+)
+
+// defaultValue is a value set by default tags on a fresh config.
+type defaultValue struct {
+	key   string
+	value any
+	// guard is the key of the innermost nil pointer struct above key. The
+	// default only applies when a source allocates it by setting a key
+	// under guard.
+	guard string
+}
+
+// collectDefaults returns the values of the default tags of the fields
+// of conf that are zero, so that values set on conf before loading are
+// kept. SetDefaults hooks are not run, they see the loaded config.
+func collectDefaults(conf any, tag, delim string) ([]defaultValue, error) {
+	t := reflect.TypeOf(conf)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	v := reflect.New(t).Elem()
+	if cv := reflect.Indirect(reflect.ValueOf(conf)); cv.Kind() == reflect.Struct {
+		v.Set(cv)
+	}
+
+	var out []defaultValue
+	err := walkDefaults(v, "", "", tag, delim, &out)
+	return out, err
+}
+
+func walkDefaults(v reflect.Value, prefix, guard, tagKey, delim string, out *[]defaultValue) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, ok := fieldName(field, tagKey)
+		if !ok {
+			continue
+		}
+
+		if isInline(field, tagKey) {
+			err := walkDefaults(v.Field(i), prefix, guard, tagKey, delim, out)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + delim + name
+		}
+
+		fv := v.Field(i)
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct && !isLeafStruct(ft) {
+			if fv.IsZero() {
+				d, err := tagDefault(field)
+				if err != nil {
+					return err
+				}
+				if d.IsValid() {
+					fv.Set(d)
+				}
+			}
+
+			g := guard
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+					g = key
+				}
+				fv = fv.Elem()
+			}
+			err := walkDefaults(fv, key, g, tagKey, delim, out)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !fv.IsZero() {
+			continue
+		}
+		d, err := tagDefault(field)
+		if err != nil {
+			return err
+		}
+		if !d.IsValid() {
+			continue
+		}
+		for d.Kind() == reflect.Ptr && !d.IsNil() {
+			d = d.Elem()
+		}
+		if d.IsZero() {
+			continue
+		}
+		*out = append(*out, defaultValue{key: key, value: d.Interface(), guard: guard})
+	}
+	return nil
+}
+
+// tagDefault returns the value the default tag of field sets on a zero
+// field, or an invalid value if it has none. It sets it on a struct
+// holding field alone, which has no SetDefaults hook.
+func tagDefault(field reflect.StructField) (reflect.Value, error) {
+	tag, ok := field.Tag.Lookup("default")
+	if !ok || tag == "" || tag == "-" {
+		return reflect.Value{}, nil
+	}
+
+	probe := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "V",
+		Type: field.Type,
+		Tag:  reflect.StructTag("default:" + strconv.Quote(tag)),
+	}}))
+	err := defaults.Set(probe.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return probe.Elem().Field(0), nil
+}
+
+// loadDefaults merges default values underneath the keys loaded so far:
+// a default only fills a key that no source provided, so explicit zero
+// values are kept. Items of slices and maps of structs are filled the
+// same way.
+func (c *LoadContext) loadDefaults() error {
+	values, err := collectDefaults(c.Conf, c.Loader.tag, c.Loader.delim)
+	if err != nil {
+		return err
+	}
+
+	k := koanf.New(c.Loader.delim)
+	for _, d := range values {
+		if c.provided(d.key) {
+			// still the bottom of the override chain
+			if chain, ok := c.origins[d.key]; ok {
+				c.origins[d.key] = append([]Origin{{Kind: OriginDefault, Value: d.value}}, chain...)
+			}
+			continue
+		}
+		if d.guard != "" && !c.Koanf.Exists(d.guard) {
+			continue
+		}
+
+		err = k.Set(d.key, d.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.merge(k, func(string) Origin {
+		return Origin{Kind: OriginDefault}
+	})
+	if err != nil {
+		return err
+	}
+	return c.loadElemDefaults()
+}
+
+// provided reports whether a source set key or a parent of it as a
+// whole, such as a struct given as JSON.
+func (c *LoadContext) provided(key string) bool {
+	if c.Koanf.Exists(key) {
+		return true
+	}
+
+	delim := c.Loader.delim
+	for i := 0; i+len(delim) <= len(key); i++ {
+		if key[i:i+len(delim)] != delim {
+			continue
+		}
+		parent := key[:i]
+		if !c.Koanf.Exists(parent) {
+			continue
+		}
+		if _, ok := c.Koanf.Get(parent).(map[string]any); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// loadElemDefaults merges the default tags of the struct items of
+// slices and maps underneath each item, like loadDefaults does for the
+// fields of the config. Items are only known once sources are loaded.
+func (c *LoadContext) loadElemDefaults() error {
+	m := c.Koanf.Raw()
+	changed, err := elemDefaults(reflect.TypeOf(c.Conf), m, c.Loader.tag, c.Loader.delim)
+	if err != nil || !changed {
+		return err
+	}
+	return c.Koanf.Load(mapProvider(m), nil)
+}
+
+// elemDefaults fills the items of the slices and maps of structs in m,
+// the raw value of a t, in place. It reports whether any item was
+// filled.
+func elemDefaults(t reflect.Type, m map[string]any, tag, delim string) (bool, error) {
+	var changed bool
+	var err error
+	walkFields(t, "", tag, delim, func(key string, field reflect.StructField, leaf bool) {
+		if !leaf || err != nil {
+			return
+		}
+
+		var items []any
+		v := kmaps.Search(m, strings.Split(key, delim))
+		elem, ok := structElem(field.Type)
+		if ok {
+			items, _ = v.([]any)
+		} else if elem, ok = mapElem(field.Type); ok {
+			values, _ := v.(map[string]any)
+			for _, item := range values {
+				items = append(items, item)
+			}
+		}
+
+		for _, item := range items {
+			im, ok := item.(map[string]any)
+			if !ok || err != nil {
+				continue
+			}
+			var c bool
+			c, err = itemDefaults(elem, im, tag, delim)
+			changed = changed || c
+		}
+	})
+	return changed, err
+}
+
+// itemDefaults merges the default tags of t underneath item, a raw t,
+// in place. Defaults under a nil pointer struct only apply if item sets
+// a key under it.
+func itemDefaults(t reflect.Type, item map[string]any, tag, delim string) (bool, error) {
+	values, err := collectDefaults(reflect.New(t).Interface(), tag, delim)
+	if err != nil {
+		return false, err
+	}
+
+	var changed bool
+	for _, d := range values {
+		path := strings.Split(d.key, delim)
+		if d.guard != "" && kmaps.Search(item, strings.Split(d.guard, delim)) == nil {
+			continue
+		}
+		if providedIn(item, path) {
+			continue
+		}
+		kmaps.Merge(kmaps.Unflatten(map[string]any{d.key: d.value}, delim), item)
+		changed = true
+	}
+
+	c, err := elemDefaults(t, item, tag, delim)
+	return changed || c, err
+}
+
+// providedIn reports whether m sets path or a parent of it as a whole,
+// like provided.
+func providedIn(m map[string]any, path []string) bool {
+	for i := range path {
+		v, ok := m[path[i]]
+		if !ok {
+			return false
+		}
+		if i == len(path)-1 {
+			return true
+		}
+		m, ok = v.(map[string]any)
+		if !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// callSetters runs the SetDefaults hooks of the loaded config, nested
+// structs first, as defaults.Set does.
+func (c *LoadContext) callSetters() {
+	callSetters(reflect.ValueOf(c.Conf))
+}
+
+func callSetters(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			callSetters(v.Elem())
+		}
+
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				callSetters(v.Field(i))
+			}
+		}
+		if v.CanAddr() {
+			if s, ok := v.Addr().Interface().(defaults.Setter); ok {
+				s.SetDefaults()
+			}
+		}
+
+	case reflect.Slice:
+		for i := range v.Len() {
+			callSetters(v.Index(i))
+		}
+
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			// map values are not addressable
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(v.MapIndex(k))
+			callSetters(e)
+			v.SetMapIndex(k, e)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package mykonf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPath_ExplicitZeroKeepsValue(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "config.yaml")
+
+	content := []byte("enabled: false\nretries: 0\nname: \"\"\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	t.Setenv("ZERO_UID", "0")
+
+	type Config struct {
+		Enabled bool   `yaml:"enabled" default:"true"`
+		Retries int    `yaml:"retries" default:"3"`
+		Name    string `yaml:"name" default:"app"`
+		Uid     int    `yaml:"uid" default:"1000"`
+		Gid     int    `yaml:"gid" default:"1000"`
+	}
+
+	var conf Config
+	err := LoadPath("ZERO_", tmpFile, &conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Enabled {
+		t.Error("expected Enabled=false from file, got default true")
+	}
+
+	if conf.Retries != 0 {
+		t.Errorf("expected Retries=0 from file, got %d", conf.Retries)
+	}
+
+	if conf.Name != "" {
+		t.Errorf("expected empty Name from file, got %q", conf.Name)
+	}
+
+	if conf.Uid != 0 {
+		t.Errorf("expected Uid=0 from env, got %d", conf.Uid)
+	}
+
+	if conf.Gid != 1000 {
+		t.Errorf("expected Gid=1000 from default, got %d", conf.Gid)
+	}
+}
+
+func TestLoadPath_NestedPointerDefaults(t *testing.T) {
+	type Database struct {
+		Host string `yaml:"host" default:"localhost"`
+		Port int    `yaml:"port" default:"5432"`
+	}
+	type Config struct {
+		Primary  *Database `yaml:"primary"`
+		Replica  *Database `yaml:"replica"`
+		Fallback Database  `yaml:"fallback"`
+	}
+
+	t.Setenv("PTR_PRIMARY_PORT", "6543")
+
+	var conf Config
+	err := LoadPath("PTR_", "/nonexistent/config.yaml", &conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Primary == nil || conf.Primary.Host != "localhost" || conf.Primary.Port != 6543 {
+		t.Errorf("expected Primary={localhost 6543}, got %+v", conf.Primary)
+	}
+
+	if conf.Replica != nil {
+		t.Errorf("expected Replica to stay nil, got %+v", conf.Replica)
+	}
+
+	if conf.Fallback.Host != "localhost" || conf.Fallback.Port != 5432 {
+		t.Errorf("expected Fallback={localhost 5432}, got %+v", conf.Fallback)
+	}
+}
+
+func TestLoadPath_JSONStructDefaults(t *testing.T) {
+	type Config struct {
+		Gitea struct {
+			Url    string `yaml:"url" json:"url"`
+			ApiKey string `yaml:"api_key" json:"api_key" default:"api_key_here"`
+		} `yaml:"gitea"`
+	}
+
+	t.Setenv("JSONDEF_GITEA", `{"url":"http://gitea"}`)
+
+	var conf Config
+	err := LoadPath("JSONDEF_", "/nonexistent/config.yaml", &conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Gitea.Url != "http://gitea" {
+		t.Errorf("expected Gitea.Url='http://gitea', got %q", conf.Gitea.Url)
+	}
+
+	if conf.Gitea.ApiKey != "api_key_here" {
+		t.Errorf("expected Gitea.ApiKey='api_key_here', got %q", conf.Gitea.ApiKey)
+	}
+}
+
+func TestLoadPath_SliceElemDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "config.yaml")
+
+	content := []byte("upstreams:\n  - host: a\n  - host: b\n    port: 9090\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	type Upstream struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port" default:"80"`
+	}
+	type Config struct {
+		Upstreams []Upstream `yaml:"upstreams"`
+	}
+
+	var conf Config
+	err := LoadPath("TEST_", tmpFile, &conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(conf.Upstreams) != 2 || conf.Upstreams[0].Port != 80 || conf.Upstreams[1].Port != 9090 {
+		t.Errorf("expected ports [80 9090], got %+v", conf.Upstreams)
+	}
+}
+
+func TestLoadPath_ElemExplicitZeroKeepsValue(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "config.yaml")
+
+	content := []byte("ups:\n  - enabled: false\n    retries: 0\n  - {}\nnamed:\n  a:\n    enabled: false\n    retries: 0\n  b: {}\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	type Upstream struct {
+		Enabled bool `yaml:"enabled" default:"true"`
+		Retries int  `yaml:"retries" default:"3"`
+	}
+	type Config struct {
+		Ups   []Upstream          `yaml:"ups"`
+		Named map[string]Upstream `yaml:"named"`
+	}
+
+	var conf Config
+	err := LoadPath("TEST_", tmpFile, &conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zero, defaulted := Upstream{}, Upstream{Enabled: true, Retries: 3}
+	if len(conf.Ups) != 2 || conf.Ups[0] != zero || conf.Ups[1] != defaulted {
+		t.Errorf("expected ups=[%+v %+v], got %+v", zero, defaulted, conf.Ups)
+	}
+	if conf.Named["a"] != zero || conf.Named["b"] != defaulted {
+		t.Errorf("expected named={a:%+v b:%+v}, got %+v", zero, defaulted, conf.Named)
+	}
+}
+
+func TestLoadPath_PresetValueKept(t *testing.T) {
+	type Config struct {
+		Port     int `yaml:"port" default:"8080"`
+		Database struct {
+			Host string `yaml:"host" default:"localhost"`
+			User string `yaml:"user" default:"postgres"`
+		} `yaml:"database"`
+	}
+
+	conf := Config{Port: 9000}
+	conf.Database.Host = "db.internal"
+	err := LoadPath("PRESET_", filepath.Join(t.TempDir(), "missing.yaml"), &conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Port != 9000 {
+		t.Errorf("expected preset Port=9000, got %d", conf.Port)
+	}
+
+	if conf.Database.Host != "db.internal" {
+		t.Errorf("expected preset Database.Host=db.internal, got %q", conf.Database.Host)
+	}
+
+	if conf.Database.User != "postgres" {
+		t.Errorf("expected Database.User=postgres from default, got %q", conf.Database.User)
+	}
+}
+
+type setterServer struct {
+	Host string `yaml:"host" default:"localhost"`
+	URL  string `yaml:"url"`
+}
+
+func (s *setterServer) SetDefaults() {
+	if s.URL == "" {
+		s.URL = "http://" + s.Host
+	}
+}
+
+func TestLoadPath_SetDefaultsSeesLoadedValues(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "server:\n  host: api.local\nservers:\n  a: {}\n",
+	})
+
+	var conf struct {
+		Server  setterServer            `yaml:"server"`
+		Servers map[string]setterServer `yaml:"servers"`
+	}
+	err := LoadPath("SETTER_", filepath.Join(tmpDir, "config.yaml"), &conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Server.URL != "http://api.local" {
+		t.Errorf("expected Server.URL=http://api.local, got %q", conf.Server.URL)
+	}
+
+	if conf.Servers["a"].URL != "http://localhost" {
+		t.Errorf("expected Servers[a].URL=http://localhost, got %q", conf.Servers["a"].URL)
+	}
+}
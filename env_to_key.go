@@ -1,17 +1,132 @@
 package mykonf
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 )
 
 func EnvToKey(structNilPtr any, tag string) map[string]string {
+	return envToKey(structNilPtr, tag, ".")
+}
+
+func envToKey(structNilPtr any, tag, delim string) map[string]string {
 	result := make(map[string]string)
-	traverseType(reflect.TypeOf(structNilPtr), "", tag, result)
+	walkFields(reflect.TypeOf(structNilPtr), "", tag, delim, func(key string, _ reflect.StructField, _ bool) {
+		result[envName(key, delim)] = key
+	})
 	return result
 }
 
-func traverseType(t reflect.Type, jsonPrefix, tagKey string, result map[string]string) {
+// checkEnvNames fails if fields of structNilPtr share an env name, such
+// as base_home.dir and base.home_dir, since only one of them could be
+// set from env. The values of slices and maps of structs are checked
+// too, with * in place of the index or map key. Types already being
+// walked are skipped, so that recursive types such as
+// Route{Children []Route} end.
+func checkEnvNames(structNilPtr any, tag, delim string) error {
+	var errs []error
+	visited := make(map[reflect.Type]bool)
+	collectEnvNames(reflect.TypeOf(structNilPtr), "", "", tag, delim, visited, &errs)
+	return errors.Join(errs...)
+}
+
+func collectEnvNames(t reflect.Type, keyPrefix, namePrefix, tag, delim string, visited map[reflect.Type]bool, errs *[]error) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	seen := make(map[string]string)
+	walkFields(t, keyPrefix, tag, delim, func(key string, field reflect.StructField, leaf bool) {
+		name := namePrefix + envName(strings.TrimPrefix(key, keyPrefix+delim), delim)
+		if prev, ok := seen[name]; ok {
+			*errs = append(*errs, fmt.Errorf("env name %s is shared by %s and %s", name, prev, key))
+		} else {
+			seen[name] = key
+		}
+
+		if !leaf {
+			return
+		}
+		if elem, ok := structElem(field.Type); ok {
+			collectEnvNames(elem, key+"[*]", name+"_*_", tag, delim, visited, errs)
+		} else if elem, ok := mapElem(field.Type); ok {
+			collectEnvNames(elem, key+delim+"*", name+"_*_", tag, delim, visited, errs)
+		}
+	})
+}
+
+// envName returns the env name of key without prefix.
+func envName(key, delim string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, delim, "_"))
+}
+
+// fieldName returns the key name of an exported field, false if the field
+// is unexported or ignored with "-".
+func fieldName(field reflect.StructField, tagKey string) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+
+	tagValue := field.Tag.Get(tagKey)
+	name := strings.Split(tagValue, ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// squashOption returns the tag option that flattens a struct field into
+// its parent with tagKey: yaml:",inline" or mapstructure:",squash".
+func squashOption(tagKey string) string {
+	if tagKey == "yaml" {
+		return "inline"
+	}
+	return "squash"
+}
+
+// isInline reports whether field, a named field, flattens into its
+// parent like the decoder does: embedded structs without a tag name and
+// struct fields tagged with squashOption. Pointers are never flattened.
+func isInline(field reflect.StructField, tagKey string) bool {
+	if field.Type.Kind() != reflect.Struct || isLeafStruct(field.Type) {
+		return false
+	}
+	return isEmbedded(field, tagKey) || slices.Contains(strings.Split(field.Tag.Get(tagKey), ",")[1:], squashOption(tagKey))
+}
+
+// isEmbedded reports whether field is embedded without a tag name, which
+// the decoder only flattens through embeddedHookFunc.
+func isEmbedded(field reflect.StructField, tagKey string) bool {
+	return field.Anonymous && strings.Split(field.Tag.Get(tagKey), ",")[0] == ""
+}
+
+// isLeafStruct reports whether t, a struct type, is decoded as a single
+// value rather than walked.
+func isLeafStruct(t reflect.Type) bool {
+	switch t.String() {
+	// Time/Duration are leaf nodes
+	case "time.Time", "time.Duration":
+		return true
+	}
+	return false
+}
+
+// walkFields calls fn for every exported field reachable from t with its
+// key path. Nested structs are reported before their fields with leaf set
+// to false, inline ones are not reported and their fields take the key
+// path of the parent.
+func walkFields(t reflect.Type, jsonPrefix, tagKey, delim string, fn func(key string, field reflect.StructField, leaf bool)) {
 	for t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
@@ -23,22 +138,19 @@ func traverseType(t reflect.Type, jsonPrefix, tagKey string, result map[string]s
 	for i := range t.NumField() {
 		field := t.Field(i)
 
-		if field.PkgPath != "" {
+		jsonName, ok := fieldName(field, tagKey)
+		if !ok {
 			continue
 		}
 
-		tagValue := field.Tag.Get(tagKey)
-		jsonName := strings.Split(tagValue, ",")[0]
-		if jsonName == "-" {
+		if isInline(field, tagKey) {
+			walkFields(field.Type, jsonPrefix, tagKey, delim, fn)
 			continue
 		}
-		if jsonName == "" {
-			jsonName = field.Name
-		}
 
 		currentJSONPrefix := jsonName
 		if jsonPrefix != "" {
-			currentJSONPrefix = jsonPrefix + "." + currentJSONPrefix
+			currentJSONPrefix = jsonPrefix + delim + currentJSONPrefix
 		}
 
 		fieldType := field.Type
@@ -48,17 +160,13 @@ func traverseType(t reflect.Type, jsonPrefix, tagKey string, result map[string]s
 			ft = ft.Elem()
 		}
 
-		if ft.Kind() == reflect.Struct {
-			switch ft.String() {
-			// Time/Duration are leaf nodes
-			case "time.Time", "time.Duration":
-			default:
-				traverseType(fieldType, currentJSONPrefix, tagKey, result)
-			}
+		if ft.Kind() == reflect.Struct && !isLeafStruct(ft) {
+			fn(currentJSONPrefix, field, false)
+			walkFields(fieldType, currentJSONPrefix, tagKey, delim, fn)
+			continue
 		}
 
 		// leaf node
-		envKey := strings.ToUpper(strings.ReplaceAll(currentJSONPrefix, ".", "_"))
-		result[envKey] = currentJSONPrefix
+		fn(currentJSONPrefix, field, true)
 	}
 }
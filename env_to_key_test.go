@@ -1,6 +1,7 @@
 package mykonf
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -235,3 +236,134 @@ func TestEnvToKey_PointerToPointerField(t *testing.T) {
 		t.Errorf("expected result[INNER_NAME] = 'inner.name', got %q", result["INNER_NAME"])
 	}
 }
+
+func TestEnvToKey_EmbeddedAndInline(t *testing.T) {
+	type Base struct {
+		Home string `yaml:"home"`
+	}
+	type Log struct {
+		Level string `yaml:"level"`
+	}
+	type Config struct {
+		Base
+		Log  `yaml:"log"`
+		Opts Log `yaml:",inline"`
+		DB   Log `yaml:"db"`
+	}
+
+	result := EnvToKey((*Config)(nil), "yaml")
+
+	// Log has a tag name, so it is not flattened
+	expected := map[string]string{
+		"HOME":      "home",
+		"LOG":       "log",
+		"LOG_LEVEL": "log.level",
+		"LEVEL":     "level",
+		"DB":        "db",
+		"DB_LEVEL":  "db.level",
+	}
+	if len(result) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+	for k, v := range expected {
+		if result[k] != v {
+			t.Errorf("expected result[%s] = %q, got %q", k, v, result[k])
+		}
+	}
+}
+
+func TestEnvToKey_Squash(t *testing.T) {
+	type Base struct {
+		Home string `mapstructure:"home"`
+	}
+	type Config struct {
+		Shared Base `mapstructure:",squash"`
+	}
+
+	result := EnvToKey((*Config)(nil), "mapstructure")
+
+	if result["HOME"] != "home" {
+		t.Errorf("expected result[HOME] = 'home', got %q", result["HOME"])
+	}
+}
+
+func TestCheck_Collision(t *testing.T) {
+	type Config struct {
+		BaseHome struct {
+			Dir string `yaml:"dir"`
+		} `yaml:"base_home"`
+		Base struct {
+			HomeDir string `yaml:"home_dir"`
+		} `yaml:"base"`
+	}
+
+	err := Check(&Config{})
+
+	expected := "env name BASE_HOME_DIR is shared by base_home.dir and base.home_dir"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	err = New("APP_").Load(&Config{})
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected Load to fail with %q, got %v", expected, err)
+	}
+}
+
+func TestCheck_CollisionInItems(t *testing.T) {
+	type Item struct {
+		A struct {
+			B string `yaml:"b"`
+		} `yaml:"a"`
+		AB string `yaml:"a_b"`
+	}
+	type Config struct {
+		Items []Item          `yaml:"items"`
+		Named map[string]Item `yaml:"named"`
+	}
+
+	err := Check((*Config)(nil))
+
+	for _, expected := range []string{
+		"env name ITEMS_*_A_B is shared by items[*].a.b and items[*].a_b",
+		"env name NAMED_*_A_B is shared by named.*.a.b and named.*.a_b",
+	} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestCheck_NoCollision(t *testing.T) {
+	type Config struct {
+		Database struct {
+			Host string `yaml:"host"`
+		} `yaml:"database"`
+		DatabaseName string `yaml:"database_name"`
+	}
+
+	err := Check((*Config)(nil))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type route struct {
+	Path     string           `yaml:"path"`
+	Children []route          `yaml:"children"`
+	Named    map[string]route `yaml:"named"`
+}
+
+func TestCheck_RecursiveType(t *testing.T) {
+	done := make(chan error, 1)
+	go func() { done <- Check((*route)(nil)) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Check did not return for a recursive type")
+	}
+}
@@ -0,0 +1,181 @@
+package mykonf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	kmaps "github.com/knadh/koanf/maps"
+	"github.com/knadh/koanf/v2"
+)
+
+// SliceEnvPolicy controls how indexed env vars, such as
+// APP_UPSTREAMS_0_HOST, combine with the list loaded by earlier sources.
+type SliceEnvPolicy int
+
+const (
+	// SliceEnvMerge overrides the fields of existing items and appends
+	// new ones.
+	SliceEnvMerge SliceEnvPolicy = iota
+	// SliceEnvReplace replaces the list with the items set by env.
+	SliceEnvReplace
+)
+
+// sliceField is a slice of structs, whose items can be set field by
+// field from env.
+type sliceField struct {
+	key string
+	// name is the env name of key without prefix.
+	name string
+	// elem maps the env names of an item to their key paths.
+	elem map[string]string
+}
+
+// indexedVar is an env var setting a field of a slice item.
+type indexedVar struct {
+	field *sliceField
+	index int
+	// key is the key path in the item.
+	key   string
+	name  string
+	value any
+}
+
+func (c *LoadContext) sliceFields() []sliceField {
+	if c.slices == nil {
+		c.slices = []sliceField{}
+		walkFields(reflect.TypeOf(c.Conf), "", c.Loader.tag, c.Loader.delim, func(key string, field reflect.StructField, leaf bool) {
+			elem, ok := structElem(field.Type)
+			if !leaf || !ok {
+				return
+			}
+			c.slices = append(c.slices, sliceField{
+				key:  key,
+				name: envName(key, c.Loader.delim),
+				elem: envToKey(reflect.Zero(reflect.PointerTo(elem)).Interface(), c.Loader.tag, c.Loader.delim),
+			})
+		})
+	}
+	return c.slices
+}
+
+// structElem returns the item type of t if it is a slice or array of
+// structs.
+func structElem(t reflect.Type) (reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return nil, false
+	}
+	t = t.Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct && !isLeafStruct(t)
+}
+
+// indexedEnv resolves name, without prefix, of the form
+// <SLICE>_<INDEX>_<FIELD>.
+func (c *LoadContext) indexedEnv(name string) (indexedVar, bool) {
+	var best indexedVar
+	fields := c.sliceFields()
+	for i := range fields {
+		f := &fields[i]
+		rest, ok := strings.CutPrefix(name, f.name+"_")
+		if !ok || best.field != nil && len(best.field.name) > len(f.name) {
+			continue
+		}
+		index, field, ok := strings.Cut(rest, "_")
+		if !ok || strings.Trim(index, "0123456789") != "" {
+			continue
+		}
+		n, err := strconv.Atoi(index)
+		if err != nil {
+			continue
+		}
+		key, ok := f.elem[field]
+		if !ok {
+			continue
+		}
+		best = indexedVar{field: f, index: n, key: key}
+	}
+	return best, best.field != nil
+}
+
+// setIndexed assembles the items set by vars into the lists of k,
+// recording the env names of each list in names. Items set by earlier
+// sources are kept with SliceEnvMerge.
+func (c *LoadContext) setIndexed(k *koanf.Koanf, kind string, vars []indexedVar, names map[string]string) error {
+	groups := make(map[*sliceField][]indexedVar)
+	var order []*sliceField
+	for _, v := range vars {
+		if _, ok := groups[v.field]; !ok {
+			order = append(order, v.field)
+		}
+		groups[v.field] = append(groups[v.field], v)
+	}
+
+	var errs []error
+	for _, f := range order {
+		vars := groups[f]
+		slices.SortFunc(vars, func(a, b indexedVar) int { return a.index - b.index })
+
+		var envNames []string
+		for _, v := range vars {
+			envNames = append(envNames, v.name)
+		}
+		if name := names[f.key]; name != "" {
+			errs = append(errs, fmt.Errorf("%s %s and %s are both set", kind, name, strings.Join(envNames, ", ")))
+			continue
+		}
+
+		var items []any
+		if c.Loader.sliceEnv == SliceEnvMerge {
+			items, _ = c.Koanf.Get(f.key).([]any)
+		}
+
+		// every index up to the last one must be set
+		next := len(items)
+		var err error
+		for _, v := range vars {
+			if v.index > next {
+				err = fmt.Errorf("%s %s: sparse index, %s[%d] is not set", kind, v.name, f.key, next)
+				break
+			}
+			if v.index == next {
+				next++
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		list := make([]any, next)
+		for i, item := range items {
+			if m, ok := item.(map[string]any); ok {
+				item = kmaps.Copy(m)
+			}
+			list[i] = item
+		}
+		for _, v := range vars {
+			item, ok := list[v.index].(map[string]any)
+			if !ok {
+				item = make(map[string]any)
+				list[v.index] = item
+			}
+			kmaps.Merge(kmaps.Unflatten(map[string]any{v.key: v.value}, c.Loader.delim), item)
+		}
+
+		err = k.Set(f.key, list)
+		if err != nil {
+			return err
+		}
+		names[f.key] = strings.Join(envNames, ", ")
+	}
+	return errors.Join(errs...)
+}
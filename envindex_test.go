@@ -0,0 +1,92 @@
+package mykonf
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type upstreamConfig struct {
+	Upstreams []struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+		TLS  struct {
+			Enabled bool `yaml:"enabled"`
+		} `yaml:"tls"`
+	} `yaml:"upstreams"`
+}
+
+func TestLoader_IndexedEnvMerge(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "upstreams:\n  - host: a.local\n    port: 80\n",
+	})
+
+	t.Setenv("IDX_UPSTREAMS_0_PORT", "8080")
+	t.Setenv("IDX_UPSTREAMS_1_HOST", "b.local")
+	t.Setenv("IDX_UPSTREAMS_1_TLS_ENABLED", "true")
+
+	var conf upstreamConfig
+	p, err := New("IDX_",
+		WithSources(FileSource(path), EnvSource("IDX_")),
+		WithUnusedEnv(UnusedEnvError),
+	).LoadProvenance(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(conf.Upstreams) != 2 {
+		t.Fatalf("expected 2 upstreams, got %+v", conf.Upstreams)
+	}
+	if u := conf.Upstreams[0]; u.Host != "a.local" || u.Port != 8080 {
+		t.Errorf("expected upstreams[0]={a.local 8080}, got %+v", u)
+	}
+	if u := conf.Upstreams[1]; u.Host != "b.local" || !u.TLS.Enabled {
+		t.Errorf("expected upstreams[1]={b.local tls}, got %+v", u)
+	}
+
+	chain := p["upstreams"]
+	if o := chain[len(chain)-1]; o.Name != "IDX_UPSTREAMS_0_PORT, IDX_UPSTREAMS_1_HOST, IDX_UPSTREAMS_1_TLS_ENABLED" {
+		t.Errorf("expected upstreams from the indexed env vars, got %v", o)
+	}
+}
+
+func TestLoader_IndexedEnvReplace(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "upstreams:\n  - host: a.local\n    port: 80\n  - host: b.local\n",
+	})
+
+	t.Setenv("IDX_UPSTREAMS_0_HOST", "c.local")
+
+	var conf upstreamConfig
+	err := New("IDX_",
+		WithSources(FileSource(path), EnvSource("IDX_")),
+		WithSliceEnv(SliceEnvReplace),
+	).Load(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(conf.Upstreams) != 1 {
+		t.Fatalf("expected 1 upstream, got %+v", conf.Upstreams)
+	}
+	if u := conf.Upstreams[0]; u.Host != "c.local" || u.Port != 0 {
+		t.Errorf("expected upstreams[0]={c.local 0}, got %+v", u)
+	}
+}
+
+func TestLoader_IndexedEnvSparse(t *testing.T) {
+	t.Setenv("IDX_UPSTREAMS_0_HOST", "a.local")
+	t.Setenv("IDX_UPSTREAMS_2_HOST", "c.local")
+
+	var conf upstreamConfig
+	err := New("IDX_", WithSources(EnvSource("IDX_"))).Load(&conf)
+
+	expected := "env IDX_UPSTREAMS_2_HOST: sparse index, upstreams[1] is not set"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
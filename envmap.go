@@ -0,0 +1,78 @@
+package mykonf
+
+import (
+	"reflect"
+	"strings"
+)
+
+// mapField is a map of structs, whose values can be set field by field
+// from env with the map key in the name.
+type mapField struct {
+	key string
+	// name is the env name of key without prefix.
+	name string
+	// elem maps the env names of a value to their key paths.
+	elem map[string]string
+}
+
+func (c *LoadContext) mapFields() []mapField {
+	if c.maps == nil {
+		c.maps = []mapField{}
+		walkFields(reflect.TypeOf(c.Conf), "", c.Loader.tag, c.Loader.delim, func(key string, field reflect.StructField, leaf bool) {
+			elem, ok := mapElem(field.Type)
+			if !leaf || !ok {
+				return
+			}
+			c.maps = append(c.maps, mapField{
+				key:  key,
+				name: envName(key, c.Loader.delim),
+				elem: envToKey(reflect.Zero(reflect.PointerTo(elem)).Interface(), c.Loader.tag, c.Loader.delim),
+			})
+		})
+	}
+	return c.maps
+}
+
+// mapElem returns the value type of t if it is a map of structs with
+// string keys.
+func mapElem(t reflect.Type) (reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return nil, false
+	}
+	t = t.Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct && !isLeafStruct(t)
+}
+
+// mapEnv resolves name, without prefix, of the form <MAP>_<KEY>_<FIELD>
+// to a key path. KEY may contain underscores, so the longest FIELD, then
+// the longest MAP, wins. KEY is lowercased unless WithPreserveMapKeyCase
+// is set.
+func (c *LoadContext) mapEnv(name string) (string, bool) {
+	var best, bestMap, bestField string
+	fields := c.mapFields()
+	for i := range fields {
+		f := &fields[i]
+		rest, ok := strings.CutPrefix(name, f.name+"_")
+		if !ok {
+			continue
+		}
+		for field, key := range f.elem {
+			mapKey, ok := strings.CutSuffix(rest, "_"+field)
+			if !ok || mapKey == "" || len(field) < len(bestField) ||
+				len(field) == len(bestField) && len(f.name) <= len(bestMap) {
+				continue
+			}
+			if !c.Loader.preserveMapKeyCase {
+				mapKey = strings.ToLower(mapKey)
+			}
+			best, bestMap, bestField = f.key+c.Loader.delim+mapKey+c.Loader.delim+key, f.name, field
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,73 @@
+package mykonf
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+type databasesConfig struct {
+	Databases map[string]struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+		Pool struct {
+			MaxOpen int `yaml:"max_open"`
+		} `yaml:"pool"`
+	} `yaml:"databases"`
+}
+
+func TestLoader_MapEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "databases:\n  primary:\n    host: a.local\n    port: 5432\n",
+	})
+
+	t.Setenv("MAP_DATABASES_PRIMARY_HOST", "b.local")
+	t.Setenv("MAP_DATABASES_EU_WEST_POOL_MAX_OPEN", "10")
+
+	var conf databasesConfig
+	err := New("MAP_",
+		WithSources(FileSource(path), EnvSource("MAP_")),
+		WithUnusedEnv(UnusedEnvError),
+	).Load(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if db := conf.Databases["primary"]; db.Host != "b.local" || db.Port != 5432 {
+		t.Errorf("expected databases.primary={b.local 5432}, got %+v", db)
+	}
+	if db := conf.Databases["eu_west"]; db.Pool.MaxOpen != 10 {
+		t.Errorf("expected databases.eu_west.pool.max_open=10, got %+v", db)
+	}
+}
+
+func TestLoader_MapEnvPreserveCase(t *testing.T) {
+	t.Setenv("MAP_DATABASES_Primary_HOST", "a.local")
+
+	var conf databasesConfig
+	err := New("MAP_",
+		WithSources(EnvSource("MAP_")),
+		WithPreserveMapKeyCase(true),
+	).Load(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if db, ok := conf.Databases["Primary"]; !ok || db.Host != "a.local" {
+		t.Errorf("expected databases.Primary.host=a.local, got %+v", conf.Databases)
+	}
+}
+
+func TestLoader_MapEnvUnknownField(t *testing.T) {
+	t.Setenv("MAP_DATABASES_PRIMARY_HOTS", "a.local")
+
+	var conf databasesConfig
+	err := New("MAP_",
+		WithSources(EnvSource("MAP_")),
+		WithUnusedEnv(UnusedEnvError),
+	).Load(&conf)
+	if err == nil {
+		t.Error("expected an unused env error")
+	}
+}
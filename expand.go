@@ -0,0 +1,288 @@
+package mykonf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// fileRefPrefix marks references to the contents of a file, e.g.
+// ${file:/run/secrets/db_password}.
+const fileRefPrefix = "file:"
+
+// expandConf configures how a File expands environment variables.
+type expandConf struct {
+	policy ExpandPolicy
+	// prefixes, if not empty, limits expansion to the variables starting
+	// with one of them, other references are left as is.
+	prefixes []string
+	// skip, if not nil, reports the keys whose values are left as is.
+	skip func(key string) bool
+}
+
+// expander returns an expander resolving file references relative to
+// dir, nil if nothing is expanded.
+func (x expandConf) expander(dir string) *expander {
+	if x.policy == ExpandNone {
+		return nil
+	}
+	return &expander{dir: dir, strict: x.policy == ExpandStrict, prefixes: x.prefixes}
+}
+
+// expander expands shell-style references to environment variables:
+//
+//	$VAR, ${VAR}      the value of VAR
+//	${VAR:-default}   default if VAR is unset or empty
+//	${VAR:+alt}       alt if VAR is set and not empty
+//	${VAR:?message}   fails with message if VAR is unset or empty
+//	${file:path}      the contents of path without trailing newlines
+//	$$                a literal $
+//
+// $${a.b} and $${.name} are kept, for the key references resolved after
+// loading to read them as a literal ${a.b} and ${.name}.
+//
+// default and alt are expanded too.
+type expander struct {
+	// dir resolves relative file references.
+	dir string
+	// strict fails on unset variables referenced without a default.
+	strict   bool
+	prefixes []string
+
+	unset []string
+	errs  []error
+	// read is set once a file reference is expanded.
+	read bool
+}
+
+// expand expands s as text, resolving file references relative to dir.
+func (x expandConf) expand(s, dir string) (string, error) {
+	e := x.expander(dir)
+	if e == nil {
+		return s, nil
+	}
+	s = e.expand(s)
+	return s, e.err()
+}
+
+// expandValues expands the strings in v, a parsed config value at key,
+// and returns it. Maps and slices are expanded in place, keys never are.
+func (e *expander) expandValues(v any, key, delim string, skip func(key string) bool) any {
+	if skip != nil && key != "" && skip(key) {
+		return v
+	}
+
+	switch v := v.(type) {
+	case string:
+		s, read := e.expandValue(v)
+		if read {
+			return protected{s}
+		}
+		return s
+	case map[string]any:
+		for k, sub := range v {
+			sk := k
+			if key != "" {
+				sk = key + delim + k
+			}
+			v[k] = e.expandValues(sub, sk, delim, skip)
+		}
+	case []any:
+		// items are set by the key of the slice
+		for i, sub := range v {
+			v[i] = e.expandValues(sub, key, delim, skip)
+		}
+	}
+	return v
+}
+
+// expandValue expands s, a string value, and reports whether it read a
+// file, whose contents must not be shown.
+func (e *expander) expandValue(s string) (string, bool) {
+	e.read = false
+	s = e.expand(s)
+	return s, e.read
+}
+
+func (e *expander) err() error {
+	errs := e.errs
+	if len(e.unset) > 0 {
+		errs = append(errs, fmt.Errorf("unset variables: %s", strings.Join(e.unset, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+func (e *expander) expand(s string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		switch c := s[i+1]; {
+		case c == '$':
+			if i+2 < len(s) && s[i+2] == '{' {
+				if end := closingBrace(s, i+3); end >= 0 && keyRef(s[i+3:end]) {
+					b.WriteString(s[i : end+1])
+					i = end
+					continue
+				}
+			}
+			b.WriteByte('$')
+			i++
+
+		case c == '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			b.WriteString(e.param(s[i+2 : end]))
+			i = end
+
+		case isNameStart(c):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			if e.allowed(s[i+1 : j]) {
+				b.WriteString(e.lookup(s[i+1 : j]))
+			} else {
+				b.WriteString(s[i:j])
+			}
+			i = j - 1
+
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String()
+}
+
+// keyRef reports whether body, of ${body}, can never name an
+// environment variable or a file, as with key references like ${a.b}.
+func keyRef(body string) bool {
+	if strings.HasPrefix(body, fileRefPrefix) {
+		return false
+	}
+	n := 0
+	for n < len(body) && isNameChar(body[n]) {
+		n++
+	}
+	name, op := body[:n], body[n:]
+	if name == "" || !isNameStart(name[0]) {
+		return true
+	}
+	return op != "" && (len(op) < 2 || op[0] != ':')
+}
+
+// param expands the body of ${...}.
+func (e *expander) param(body string) string {
+	if path, ok := strings.CutPrefix(body, fileRefPrefix); ok {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(e.dir, path)
+		}
+		v, err := readSecretFile(path)
+		if err != nil {
+			e.errs = append(e.errs, err)
+		}
+		e.read = true
+		return v
+	}
+
+	n := 0
+	for n < len(body) && isNameChar(body[n]) {
+		n++
+	}
+	name, op := body[:n], body[n:]
+	if name == "" || !isNameStart(name[0]) || !e.allowed(name) {
+		return "${" + body + "}"
+	}
+	if op == "" {
+		return e.lookup(name)
+	}
+	if len(op) < 2 || op[0] != ':' {
+		return "${" + body + "}"
+	}
+
+	v := os.Getenv(name)
+	word := op[2:]
+	switch op[1] {
+	case '-':
+		if v == "" {
+			return e.expand(word)
+		}
+		return v
+	case '+':
+		if v != "" {
+			return e.expand(word)
+		}
+		return ""
+	case '?':
+		if v == "" {
+			msg := e.expand(word)
+			if msg == "" {
+				msg = "not set"
+			}
+			e.errs = append(e.errs, fmt.Errorf("%s: %s", name, msg))
+		}
+		return v
+	}
+	return "${" + body + "}"
+}
+
+// allowed reports whether name may be expanded.
+func (e *expander) allowed(name string) bool {
+	if len(e.prefixes) == 0 {
+		return true
+	}
+	for _, p := range e.prefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *expander) lookup(name string) string {
+	v, ok := os.LookupEnv(name)
+	if !ok && e.strict && !slices.Contains(e.unset, name) {
+		e.unset = append(e.unset, name)
+	}
+	return v
+}
+
+// closingBrace returns the index of the } closing the ${ before i,
+// skipping nested ${...}, or -1.
+func closingBrace(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || '0' <= c && c <= '9'
+}
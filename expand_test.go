@@ -0,0 +1,228 @@
+package mykonf
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("EXP_HOST", "db.local")
+	t.Setenv("EXP_EMPTY", "")
+
+	tests := []struct {
+		in, out string
+	}{
+		{"$EXP_HOST", "db.local"},
+		{"${EXP_HOST}:5432", "db.local:5432"},
+		{"${EXP_UNSET}", ""},
+		{"${EXP_UNSET:-localhost}", "localhost"},
+		{"${EXP_EMPTY:-localhost}", "localhost"},
+		{"${EXP_HOST:-localhost}", "db.local"},
+		{"${EXP_UNSET:-${EXP_HOST}}", "db.local"},
+		{"${EXP_HOST:+tls}", "tls"},
+		{"${EXP_UNSET:+tls}", ""},
+		{"pa$$word", "pa$word"},
+		{"$$EXP_HOST", "$EXP_HOST"},
+		{"$${EXP_HOST}", "${EXP_HOST}"},
+		{"$${.name} $${a.b}", "$${.name} $${a.b}"},
+		{"cost: 5$", "cost: 5$"},
+		{"$1 and ${", "$1 and ${"},
+	}
+	for _, tt := range tests {
+		out, err := expandConf{}.expand(tt.in, "")
+		if err != nil {
+			t.Errorf("expand(%q): unexpected error: %v", tt.in, err)
+			continue
+		}
+		if out != tt.out {
+			t.Errorf("expand(%q): expected %q, got %q", tt.in, tt.out, out)
+		}
+	}
+}
+
+func TestExpandEnv_Required(t *testing.T) {
+	t.Setenv("EXP_EMPTY", "")
+
+	_, err := expandConf{}.expand("a: ${EXP_UNSET:?is required}\nb: ${EXP_EMPTY:?}\n", "")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	msg := err.Error()
+	for _, e := range []string{"EXP_UNSET: is required", "EXP_EMPTY: not set"} {
+		if !strings.Contains(msg, e) {
+			t.Errorf("expected error to contain %q, got:\n%s", e, msg)
+		}
+	}
+}
+
+func TestExpandEnv_Strict(t *testing.T) {
+	t.Setenv("EXP_HOST", "db.local")
+
+	_, err := expandConf{policy: ExpandStrict}.expand("$EXP_HOST ${EXP_USER} $EXP_PASSWORD ${EXP_USER} ${EXP_PORT:-5432}", "")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	expected := "unset variables: EXP_USER, EXP_PASSWORD"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestLoader_ExpandStrict(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "name: ${EXP_NAME}\n",
+	})
+
+	var conf struct {
+		Name string `yaml:"name"`
+	}
+	err := New("EXP_", WithSources(FileSource(path)), WithExpand(ExpandStrict)).Load(&conf)
+	if err == nil || !strings.Contains(err.Error(), path+": unset variables: EXP_NAME") {
+		t.Errorf("expected unset EXP_NAME error, got %v", err)
+	}
+
+	t.Setenv("EXP_NAME", "app")
+	err = New("EXP_", WithSources(FileSource(path)), WithExpand(ExpandStrict)).Load(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conf.Name != "app" {
+		t.Errorf("expected Name=app, got %q", conf.Name)
+	}
+}
+
+func TestLoader_ExpandScalarsOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "# set ${EXP_REQUIRED:?} before starting\n" +
+			"name: $EXP_INJECT\n" +
+			"$EXP_KEY: value\n" +
+			"labels: [$EXP_LABEL]\n",
+	})
+
+	t.Setenv("EXP_INJECT", "app\nadmin: true")
+	t.Setenv("EXP_KEY", "name")
+	t.Setenv("EXP_LABEL", "a: b")
+
+	var conf struct {
+		Name   string   `yaml:"name"`
+		Admin  bool     `yaml:"admin"`
+		Labels []string `yaml:"labels"`
+	}
+	p, err := New("EXP_", WithSources(FileSource(path))).LoadProvenance(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "app\nadmin: true" {
+		t.Errorf("expected Name=%q, got %q", "app\nadmin: true", conf.Name)
+	}
+	if conf.Admin {
+		t.Error("expected expanded value not to set admin")
+	}
+	if len(conf.Labels) != 1 || conf.Labels[0] != "a: b" {
+		t.Errorf("expected Labels=[a: b], got %v", conf.Labels)
+	}
+	if _, ok := p["$EXP_KEY"]; !ok {
+		t.Errorf("expected key $EXP_KEY not to be expanded, got %v", p)
+	}
+}
+
+func TestLoader_ExpandFalseTag(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.json")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.json": `{"password": "pa$word", "template": {"body": "Hi $USER"}, "host": "$EXP_HOST"}`,
+	})
+
+	t.Setenv("EXP_HOST", "db.local")
+
+	var conf struct {
+		Password string `yaml:"password" expand:"false"`
+		Template struct {
+			Body string `yaml:"body"`
+		} `yaml:"template" expand:"false"`
+		Host string `yaml:"host"`
+	}
+	err := New("EXP_", WithSources(FileSource(path))).Load(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Password != "pa$word" {
+		t.Errorf("expected Password=pa$word, got %q", conf.Password)
+	}
+	if conf.Template.Body != "Hi $USER" {
+		t.Errorf("expected Template.Body='Hi $USER', got %q", conf.Template.Body)
+	}
+	if conf.Host != "db.local" {
+		t.Errorf("expected Host=db.local, got %q", conf.Host)
+	}
+}
+
+func TestLoader_ExpandFalseTagInclude(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "tpl: !include t.yaml\nhost: $EXP_HOST\n",
+		"t.yaml":      "body: Hi $HOME\n",
+	})
+
+	t.Setenv("EXP_HOST", "db.local")
+	t.Setenv("HOME", "/root")
+
+	var conf struct {
+		Tpl struct {
+			Body string `yaml:"body"`
+		} `yaml:"tpl" expand:"false"`
+		Host string `yaml:"host"`
+	}
+	err := New("EXP_", WithSources(FileSource(path))).Load(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Tpl.Body != "Hi $HOME" {
+		t.Errorf("expected Tpl.Body='Hi $HOME', got %q", conf.Tpl.Body)
+	}
+	if conf.Host != "db.local" {
+		t.Errorf("expected Host=db.local, got %q", conf.Host)
+	}
+}
+
+func TestLoader_ExpandPrefixes(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "host: ${EXP_HOST}\nhome: $HOME\nshell: ${SHELL:-sh}\n",
+	})
+
+	t.Setenv("EXP_HOST", "db.local")
+	t.Setenv("HOME", "/root")
+
+	var conf struct {
+		Host  string `yaml:"host"`
+		Home  string `yaml:"home"`
+		Shell string `yaml:"shell"`
+	}
+	err := New("EXP_", WithSources(FileSource(path)), WithExpandPrefixes("EXP_")).Load(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Host != "db.local" {
+		t.Errorf("expected Host=db.local, got %q", conf.Host)
+	}
+	if conf.Home != "$HOME" {
+		t.Errorf("expected Home=$HOME, got %q", conf.Home)
+	}
+	if conf.Shell != "${SHELL:-sh}" {
+		t.Errorf("expected Shell=${SHELL:-sh}, got %q", conf.Shell)
+	}
+}
@@ -1,23 +1,141 @@
 package mykonf
 
 import (
-	"os"
+	"fmt"
+	"path/filepath"
 
 	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+	"go.yaml.in/yaml/v3"
 )
 
 type File struct {
 	*file.File
+	path   string
+	expand expandConf
+	// values only expands string values once parsed by Read, ReadBytes
+	// fails.
+	values bool
+	// dec, if not nil, decrypts the values of the file.
+	dec *decrypter
 }
 
+// Provider returns a File that expands environment variables in the
+// contents, with the shell-style modifiers listed in ExpandEnv.
 func Provider(path string) File {
-	return File{File: file.Provider(path)}
+	return newFile(path, expandConf{policy: ExpandEnv})
 }
 
+// RawProvider returns a File that reads the contents as is.
+func RawProvider(path string) File {
+	return newFile(path, expandConf{policy: ExpandNone})
+}
+
+// StrictProvider returns a File that expands like Provider, but fails
+// listing every unset variable referenced without a default.
+func StrictProvider(path string) File {
+	return newFile(path, expandConf{policy: ExpandStrict})
+}
+
+// ValueProvider returns a File that expands environment variables with
+// policy in string values once parsed, like Loader does, so that values
+// cannot change the structure of the file. Load it without a parser,
+// k.Load(ValueProvider(path, ExpandEnv), nil), its ReadBytes fails.
+func ValueProvider(path string, policy ExpandPolicy) File {
+	f := newFile(path, expandConf{policy: policy})
+	f.values = true
+	return f
+}
+
+func newFile(path string, expand expandConf) File {
+	return File{File: file.Provider(path), path: path, expand: expand}
+}
+
+// Path returns the path the File was created with.
+func (f File) Path() string { return f.path }
+
+// ReadBytes returns the contents with environment variables expanded as
+// text, for use with any koanf parser. Read and Loader expand string
+// values after parsing instead, so that values cannot change the
+// structure of the file.
 func (f File) ReadBytes() (b []byte, err error) {
+	if f.values {
+		return nil, fmt.Errorf("%s: a ValueProvider must be loaded without a parser", f.path)
+	}
 	b, err = f.File.ReadBytes()
 	if err != nil {
 		return nil, err
 	}
-	return []byte(os.ExpandEnv(string(b))), nil
+	s, err := f.expand.expand(string(b), filepath.Dir(f.path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.path, err)
+	}
+	return []byte(s), nil
+}
+
+// parse parses the file with pa, then expands and decrypts its string
+// values.
+func (f File) parse(pa koanf.Parser, delim string) (map[string]any, error) {
+	b, err := f.File.ReadBytes()
+	if err != nil {
+		return nil, err
+	}
+	m, err := pa.Unmarshal(b)
+	if err != nil {
+		return nil, fileProblem(f.path, b, err)
+	}
+
+	e := f.expand.expander(filepath.Dir(f.path))
+	if e != nil {
+		e.expandValues(m, "", delim, f.expand.skip)
+		if err := e.err(); err != nil {
+			return nil, fileProblem(f.path, nil, err)
+		}
+	}
+
+	if f.dec != nil {
+		// SOPS files are json or yaml, whose MAC needs the key order
+		var doc *yaml.Node
+		if _, ok := m[sopsKey]; ok {
+			doc = new(yaml.Node)
+			err = yaml.Unmarshal(b, doc)
+			if err != nil {
+				return nil, fileProblem(f.path, b, err)
+			}
+		}
+		err = f.dec.decryptFile(m, doc, delim)
+		if err != nil {
+			return nil, fileProblem(f.path, nil, err)
+		}
+	}
+	return m, nil
+}
+
+// Read parses the file as yaml, splicing in !include tags and loading the
+// top-level extends key underneath. String values are expanded after
+// parsing, keys and comments never are.
+func (f File) Read() (map[string]any, error) {
+	m, _, _, err := f.read(".")
+	unprotect(m, "", ".", nil)
+	return m, err
+}
+
+// read is Read that also returns the positions of keys joined by delim
+// and every file opened.
+func (f File) read(delim string) (map[string]any, map[string]position, []string, error) {
+	r := includeReader{open: f.open, delim: delim, expand: f.expand, dec: f.dec}
+	v, pos, err := r.read(f.path, "")
+	if err != nil {
+		return nil, nil, r.files, err
+	}
+	m, err := toMap(f.path, v)
+	return m, pos, r.files, err
+}
+
+// open returns a File for path with the same expansion and decryption
+// as f.
+func (f File) open(path string) File {
+	o := newFile(path, f.expand)
+	o.dec = f.dec
+	return o
 }
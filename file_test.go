@@ -3,7 +3,10 @@ package mykonf
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/knadh/koanf/v2"
 )
 
 func TestProvider(t *testing.T) {
@@ -194,3 +197,84 @@ func TestFile_ReadBytes_MultipleEnvVars(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, string(result))
 	}
 }
+
+func TestFile_ReadBytes_FileRef(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "test.yaml")
+	secret := filepath.Join(tmpDir, "db_password")
+
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0644); err != nil {
+		t.Fatalf("failed to create secret file: %v", err)
+	}
+	content := []byte("a: ${file:" + secret + "}\nb: ${file:db_password}\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	provider := Provider(tmpFile)
+	result, err := provider.ReadBytes()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "a: s3cret\nb: s3cret\n"
+	if string(result) != expected {
+		t.Errorf("expected %q, got %q", expected, string(result))
+	}
+}
+
+func TestFile_ReadBytes_FileRefMissing(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "test.yaml")
+
+	content := []byte("a: ${file:/nonexistent/secret}\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	provider := Provider(tmpFile)
+	_, err := provider.ReadBytes()
+
+	if err == nil || !strings.Contains(err.Error(), "/nonexistent/secret") {
+		t.Errorf("expected error naming /nonexistent/secret, got %v", err)
+	}
+}
+
+func TestValueProvider_ReadBytes(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "test.yaml")
+
+	if err := os.WriteFile(tmpFile, []byte("name: $TEST_VAR\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	_, err := ValueProvider(tmpFile, ExpandEnv).ReadBytes()
+
+	if err == nil || !strings.Contains(err.Error(), "without a parser") {
+		t.Errorf("expected error asking to load without a parser, got %v", err)
+	}
+}
+
+func TestValueProvider_KoanfLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "test.yaml")
+
+	t.Setenv("TEST_VAR", "a\nb: injected")
+
+	content := []byte("name: $TEST_VAR\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	k := koanf.New(".")
+	err := k.Load(ValueProvider(tmpFile, ExpandEnv), nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if k.String("name") != "a\nb: injected" || k.Exists("b") {
+		t.Errorf("expected name to hold the value as is, got %v", k.All())
+	}
+}
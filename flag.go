@@ -0,0 +1,106 @@
+package mykonf
+
+import (
+	"flag"
+	"path/filepath"
+	"reflect"
+)
+
+const configFlag = "config"
+
+// Flags holds the flags generated from a config struct by BindFlags.
+type Flags struct {
+	values []*flagValue
+	config flagValue
+}
+
+// flagValue keeps the raw string of a flag, it is parsed later by the
+// decode hooks like an environment variable.
+type flagValue struct {
+	key    string
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (v *flagValue) String() string { return v.value }
+
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	v.set = true
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// BindFlags registers a flag named by the key path for every leaf field
+// of conf on fs, such as --port and --database.host, plus a --config flag
+// listing config files. The usage text comes from the usage tag and the
+// shown default from the default tag. Flags already defined on fs are
+// left alone.
+//
+// Once bound, the default sources of l use --config instead of
+// ConfigPath when it is set, and end with the flags, above env.
+//
+// For pflag, bind to a flag.FlagSet and add it with AddGoFlagSet.
+func (l *Loader) BindFlags(fs *flag.FlagSet, conf any) *Flags {
+	f := &Flags{}
+
+	if fs.Lookup(configFlag) == nil {
+		fs.Var(&f.config, configFlag, "config files, separated by "+string(filepath.ListSeparator))
+	}
+
+	walkFields(reflect.TypeOf(conf), "", l.tag, l.delim, func(key string, field reflect.StructField, leaf bool) {
+		if !leaf || fs.Lookup(key) != nil {
+			return
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		v := &flagValue{
+			key:    key,
+			value:  field.Tag.Get("default"),
+			isBool: ft.Kind() == reflect.Bool,
+		}
+		fs.Var(v, key, field.Tag.Get("usage"))
+		f.values = append(f.values, v)
+	})
+
+	l.flags = f
+	return f
+}
+
+// ConfigPaths returns the files given by --config, nil if not set.
+func (f *Flags) ConfigPaths() []string {
+	if !f.config.set {
+		return nil
+	}
+
+	var paths []string
+	for _, p := range filepath.SplitList(f.config.value) {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// FlagSource loads the flags that were set on the command line.
+func FlagSource(f *Flags) Source {
+	return SourceFunc(func(c *LoadContext) error {
+		for _, v := range f.values {
+			if !v.set {
+				continue
+			}
+			err := c.Koanf.Set(v.key, v.value)
+			if err != nil {
+				return err
+			}
+			c.record(v.key, Origin{Kind: OriginFlag, Name: "--" + v.key}, v.value)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,97 @@
+package mykonf
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flagConfig struct {
+	Port     int           `yaml:"port" default:"8080" usage:"listen port"`
+	Debug    bool          `yaml:"debug"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Tags     []string      `yaml:"tags"`
+	Database *struct {
+		Host string `yaml:"host" default:"localhost"`
+	} `yaml:"database"`
+}
+
+func TestBindFlags_Register(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	New("FLAG_").BindFlags(fs, (*flagConfig)(nil))
+
+	for _, name := range []string{"config", "port", "debug", "timeout", "tags", "database.host"} {
+		if fs.Lookup(name) == nil {
+			t.Errorf("expected flag --%s to be registered", name)
+		}
+	}
+
+	if fs.Lookup("database") != nil {
+		t.Error("nested struct should not be registered as a flag")
+	}
+
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+
+	usage := buf.String()
+	if !strings.Contains(usage, "listen port (default 8080)") {
+		t.Errorf("expected usage to show the default tag, got:\n%s", usage)
+	}
+	if !strings.Contains(usage, "(default localhost)") {
+		t.Errorf("expected usage to show nested default, got:\n%s", usage)
+	}
+}
+
+func TestBindFlags_Load(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "flags.yaml")
+
+	content := []byte("port: 7070\ndatabase:\n  host: filehost\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	t.Setenv("FLAG_PORT", "8081")
+	t.Setenv("FLAG_TIMEOUT", "5s")
+
+	loader := New("FLAG_")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader.BindFlags(fs, (*flagConfig)(nil))
+
+	err := fs.Parse([]string{"--config", tmpFile, "--port", "9090", "--debug", "--tags=a,b"})
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	var conf flagConfig
+	err = loader.Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Port != 9090 {
+		t.Errorf("expected Port=9090 from flag, got %d", conf.Port)
+	}
+
+	if !conf.Debug {
+		t.Error("expected Debug=true from bool flag")
+	}
+
+	if conf.Timeout != 5*time.Second {
+		t.Errorf("expected Timeout=5s from env, got %v", conf.Timeout)
+	}
+
+	if len(conf.Tags) != 2 || conf.Tags[1] != "b" {
+		t.Errorf("expected Tags=[a b], got %v", conf.Tags)
+	}
+
+	if conf.Database == nil || conf.Database.Host != "filehost" {
+		t.Errorf("expected Database.Host='filehost' from --config, got %+v", conf.Database)
+	}
+}
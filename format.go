@@ -0,0 +1,63 @@
+package mykonf
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/dotenv"
+	"github.com/knadh/koanf/parsers/hcl"
+	"github.com/knadh/koanf/parsers/json"
+	"github.com/knadh/koanf/parsers/toml/v2"
+	"github.com/knadh/koanf/v2"
+)
+
+// Format is a config file format.
+type Format string
+
+const (
+	FormatYAML   Format = "yaml"
+	FormatJSON   Format = "json"
+	FormatTOML   Format = "toml"
+	FormatHCL    Format = "hcl"
+	FormatDotenv Format = "dotenv"
+)
+
+// FormatOf returns the format for the extension of path. Files named
+// .env or .env.* are dotenv, anything unknown is yaml.
+func FormatOf(path string) Format {
+	base := filepath.Base(path)
+	if base == ".env" || strings.HasPrefix(base, ".env.") {
+		return FormatDotenv
+	}
+
+	switch strings.ToLower(filepath.Ext(base)) {
+	case ".json":
+		return FormatJSON
+	case ".toml":
+		return FormatTOML
+	case ".hcl":
+		return FormatHCL
+	case ".env":
+		return FormatDotenv
+	}
+	return FormatYAML
+}
+
+// parser returns the koanf parser for f, nil for yaml which is read by
+// File itself to resolve includes.
+func (c *LoadContext) parser(f Format) koanf.Parser {
+	switch f {
+	case FormatJSON:
+		return json.Parser()
+	case FormatTOML:
+		return toml.Parser()
+	case FormatHCL:
+		return hcl.Parser(true)
+	case FormatDotenv:
+		// dotenv names map to keys like environment variables
+		return dotenv.ParserEnvWithValue("", c.Loader.delim, func(k, v string) (string, any) {
+			return c.envKey(k), v
+		})
+	}
+	return nil
+}
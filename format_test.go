@@ -0,0 +1,102 @@
+package mykonf
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]Format{
+		"config.yaml":      FormatYAML,
+		"config.yml":       FormatYAML,
+		"config":           FormatYAML,
+		"/etc/app/c.json":  FormatJSON,
+		"config.TOML":      FormatTOML,
+		"config.hcl":       FormatHCL,
+		"app.env":          FormatDotenv,
+		".env":             FormatDotenv,
+		"/srv/.env.prod":   FormatDotenv,
+		"config.prod.yaml": FormatYAML,
+	}
+
+	for path, expected := range tests {
+		if got := FormatOf(path); got != expected {
+			t.Errorf("FormatOf(%q): expected %q, got %q", path, expected, got)
+		}
+	}
+}
+
+type formatConfig struct {
+	Name     string `yaml:"name"`
+	Database struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	} `yaml:"database"`
+}
+
+func testLoadFormat(t *testing.T, name, content string, opts ...Option) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{name: content})
+
+	var conf formatConfig
+	opts = append(opts, WithSources(FileSource(filepath.Join(tmpDir, name))))
+	err := New("TEST_", opts...).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "app" {
+		t.Errorf("expected Name='app', got %q", conf.Name)
+	}
+
+	if conf.Database.Host != "dbhost" {
+		t.Errorf("expected Database.Host='dbhost', got %q", conf.Database.Host)
+	}
+
+	if conf.Database.Port != 5432 {
+		t.Errorf("expected Database.Port=5432, got %d", conf.Database.Port)
+	}
+}
+
+func TestLoader_JSONFile(t *testing.T) {
+	testLoadFormat(t, "config.json", `{"name": "app", "database": {"host": "dbhost", "port": 5432}}`)
+}
+
+func TestLoader_TOMLFile(t *testing.T) {
+	testLoadFormat(t, "config.toml", "name = \"app\"\n\n[database]\nhost = \"dbhost\"\nport = 5432\n")
+}
+
+func TestLoader_HCLFile(t *testing.T) {
+	testLoadFormat(t, "config.hcl", "name = \"app\"\n\ndatabase {\n  host = \"dbhost\"\n  port = 5432\n}\n")
+}
+
+func TestLoader_DotenvFile(t *testing.T) {
+	testLoadFormat(t, "config.env", "NAME=app\nDATABASE_HOST=dbhost\nDATABASE_PORT=5432\n")
+}
+
+func TestLoader_WithFormat(t *testing.T) {
+	testLoadFormat(t, "config.conf", "name = \"app\"\n\n[database]\nhost = \"dbhost\"\nport = 5432\n",
+		WithFormat(FormatTOML))
+}
+
+func TestLoader_LayerFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{"config": `{"name": "app"}`})
+
+	var conf formatConfig
+	err := New("TEST_", WithSources(FilesSource(Layer{
+		Path:   filepath.Join(tmpDir, "config"),
+		Format: FormatJSON,
+	}))).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "app" {
+		t.Errorf("expected Name='app', got %q", conf.Name)
+	}
+}
@@ -0,0 +1,347 @@
+package mykonf
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	kmaps "github.com/knadh/koanf/maps"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	includeTag = "!include"
+	extendsKey = "extends"
+	// maxAliases caps the aliases resolved by an includeReader, so that
+	// nested aliases cannot blow up like they would with yaml.v3.
+	maxAliases = 10000
+)
+
+// position is where a key is set in a yaml file.
+type position struct {
+	file         string
+	line, column int
+}
+
+// includeReader reads yaml files, resolving !include tags and the
+// top-level extends key relative to the including file.
+type includeReader struct {
+	open   func(path string) File
+	delim  string
+	expand expandConf
+	// exp expands the values of the file being read.
+	exp *expander
+	// base is the key the file being read is included at, so that
+	// expand.skip sees full key paths.
+	base string
+	dec  *decrypter
+	// stack holds the files being read, used to report include cycles.
+	stack []string
+	// anchors holds the anchors being resolved, used to report anchors
+	// containing themselves.
+	anchors []*yaml.Node
+	aliases int
+	// files holds every file opened.
+	files []string
+}
+
+// read returns the value of the yaml file at path, included at base, and
+// the positions of its keys relative to base, joined by delim.
+func (r *includeReader) read(path, base string) (any, map[string]position, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, p := range r.stack {
+		if p == abs {
+			chain := append(append([]string{}, r.stack[i:]...), abs)
+			return nil, nil, fmt.Errorf("include cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	r.stack = append(r.stack, abs)
+	r.files = append(r.files, abs)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+
+	// values are expanded once parsed
+	b, err := r.open(abs).File.ReadBytes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var doc yaml.Node
+	err = yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return nil, nil, fileProblem(path, b, err)
+	}
+
+	dir := filepath.Dir(abs)
+	exp, prev := r.exp, r.base
+	r.exp, r.base = r.expand.expander(dir), base
+	defer func() { r.exp, r.base = exp, prev }()
+
+	pos := make(map[string]position)
+	v, err := r.value(&doc, dir, abs, "", pos)
+	if err != nil {
+		return nil, nil, fileProblem(path, nil, err)
+	}
+	if r.exp != nil {
+		if err := r.exp.err(); err != nil {
+			return nil, nil, fileProblem(path, nil, err)
+		}
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v, pos, nil
+	}
+
+	if r.dec != nil {
+		err = r.dec.decryptFile(m, &doc, r.delim)
+		if err != nil {
+			return nil, nil, fileProblem(path, nil, err)
+		}
+		for k := range pos {
+			if k == sopsKey || strings.HasPrefix(k, sopsKey+r.delim) {
+				delete(pos, k)
+			}
+		}
+	}
+	v, pos, err = r.extend(m, dir, pos)
+	if err != nil {
+		return nil, nil, fileProblem(path, nil, err)
+	}
+	return v, pos, nil
+}
+
+// extend loads the files named by the extends key of m underneath m.
+func (r *includeReader) extend(m map[string]any, dir string, pos map[string]position) (any, map[string]position, error) {
+	ext, ok := m[extendsKey]
+	if !ok {
+		return m, pos, nil
+	}
+	delete(m, extendsKey)
+	delete(pos, extendsKey)
+
+	if p, ok := ext.(protected); ok {
+		ext = p.v
+	}
+	var parents []string
+	switch ext := ext.(type) {
+	case string:
+		parents = []string{ext}
+	case []any:
+		for _, p := range ext {
+			s, ok := p.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("%s: expected file path, got %v", extendsKey, p)
+			}
+			parents = append(parents, s)
+		}
+	default:
+		return nil, nil, fmt.Errorf("%s: expected file path, got %v", extendsKey, ext)
+	}
+
+	base := make(map[string]any)
+	basePos := make(map[string]position)
+	for _, p := range parents {
+		v, ppos, err := r.read(resolvePath(dir, p), r.base)
+		if err != nil {
+			return nil, nil, err
+		}
+		pm, err := toMap(p, v)
+		if err != nil {
+			return nil, nil, err
+		}
+		kmaps.Merge(pm, base)
+		maps.Copy(basePos, ppos)
+	}
+	kmaps.Merge(m, base)
+	maps.Copy(basePos, pos)
+	return base, basePos, nil
+}
+
+// value converts n to a plain value, recording the positions of the keys
+// of its mappings, under key, in pos if not nil.
+func (r *includeReader) value(n *yaml.Node, dir, file, key string, pos map[string]position) (any, error) {
+	switch n.Kind {
+	case 0:
+		// empty file
+		return nil, nil
+
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return r.value(n.Content[0], dir, file, key, pos)
+
+	case yaml.AliasNode:
+		if slices.Contains(r.anchors, n.Alias) {
+			return nil, fmt.Errorf("line %d: anchor %q contains itself", n.Line, n.Value)
+		}
+		r.aliases++
+		if r.aliases > maxAliases {
+			return nil, fmt.Errorf("line %d: document contains excessive aliasing", n.Line)
+		}
+		r.anchors = append(r.anchors, n.Alias)
+		defer func() { r.anchors = r.anchors[:len(r.anchors)-1] }()
+		return r.value(n.Alias, dir, file, key, pos)
+
+	case yaml.ScalarNode:
+		if n.Tag == includeTag {
+			path, _ := r.expandValue("", n.Value)
+			v, ipos, err := r.read(resolvePath(dir, path), r.join(r.base, key))
+			if err != nil {
+				return nil, err
+			}
+			if pos != nil {
+				for k, p := range ipos {
+					pos[r.join(key, k)] = p
+				}
+			}
+			return v, nil
+		}
+		if n.ShortTag() == "!!str" {
+			s, read := r.expandValue(key, n.Value)
+			if read {
+				return protected{s}, nil
+			}
+			return s, nil
+		}
+		var v any
+		err := n.Decode(&v)
+		return v, err
+
+	case yaml.SequenceNode:
+		s := make([]any, 0, len(n.Content))
+		for i, c := range n.Content {
+			// items are not keys, but are expanded as their slice
+			v, err := r.value(c, dir, file, key, nil)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+			if pos != nil {
+				r.itemPositions(c, fmt.Sprintf("%s[%d]", key, i), file, pos)
+			}
+		}
+		return s, nil
+
+	case yaml.MappingNode:
+		m := make(map[string]any, len(n.Content)/2)
+		// merge keys first, explicit keys always win
+		for i := 0; i < len(n.Content); i += 2 {
+			if n.Content[i].ShortTag() != "!!merge" {
+				continue
+			}
+			err := r.mergeInto(m, n.Content[i+1], dir, file, key, pos)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for i := 0; i < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if k.ShortTag() == "!!merge" {
+				continue
+			}
+			sub := r.join(key, k.Value)
+			if pos != nil {
+				pos[sub] = position{file: file, line: k.Line, column: k.Column}
+			}
+			v, err := r.value(n.Content[i+1], dir, file, sub, pos)
+			if err != nil {
+				return nil, err
+			}
+			m[k.Value] = v
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("line %d: unsupported yaml node", n.Line)
+}
+
+// itemPositions records the positions of the keys in n, the item at key
+// of a sequence, e.g. ups[1].host.
+func (r *includeReader) itemPositions(n *yaml.Node, key, file string, pos map[string]position) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			if k.ShortTag() == "!!merge" {
+				continue
+			}
+			sub := r.join(key, k.Value)
+			pos[sub] = position{file: file, line: k.Line, column: k.Column}
+			r.itemPositions(n.Content[i+1], sub, file, pos)
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			r.itemPositions(c, fmt.Sprintf("%s[%d]", key, i), file, pos)
+		}
+	}
+}
+
+// mergeInto applies a yaml merge key value (<<) to m.
+func (r *includeReader) mergeInto(m map[string]any, n *yaml.Node, dir, file, key string, pos map[string]position) error {
+	if n.Kind == yaml.SequenceNode {
+		// earlier maps in the sequence take precedence
+		for i := len(n.Content) - 1; i >= 0; i-- {
+			err := r.mergeInto(m, n.Content[i], dir, file, key, pos)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	v, err := r.value(n, dir, file, key, pos)
+	if err != nil {
+		return err
+	}
+	src, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("line %d: map merge requires map or sequence of maps as the value", n.Line)
+	}
+	for k, v := range src {
+		m[k] = v
+	}
+	return nil
+}
+
+// expandValue expands s, the string value of key in the file being
+// read, and reports whether it read a file.
+func (r *includeReader) expandValue(key, s string) (string, bool) {
+	if r.exp == nil {
+		return s, false
+	}
+	if key = r.join(r.base, key); key != "" && r.expand.skip != nil && r.expand.skip(key) {
+		return s, false
+	}
+	return r.exp.expandValue(s)
+}
+
+func (r *includeReader) join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + r.delim + key
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// toMap returns v as the root map of a config file.
+func toMap(path string, v any) (map[string]any, error) {
+	switch v := v.(type) {
+	case nil:
+		return map[string]any{}, nil
+	case map[string]any:
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s: root must be a map, got %T", path, v)
+}
@@ -0,0 +1,192 @@
+package mykonf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+}
+
+func TestFile_Read_Include(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml":     "name: app\ndatabase: !include db/db.yaml\n",
+		"db/db.yaml":      "host: localhost\nauth: !include auth.yaml\n",
+		"db/auth.yaml":    "user: $INC_USER\n",
+		"unused/foo.yaml": "x: 1\n",
+	})
+
+	t.Setenv("INC_USER", "admin")
+
+	m, err := Provider(filepath.Join(tmpDir, "config.yaml")).Read()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	db, ok := m["database"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected database to be a map, got %T", m["database"])
+	}
+	if db["host"] != "localhost" {
+		t.Errorf("expected database.host='localhost', got %v", db["host"])
+	}
+	auth, ok := db["auth"].(map[string]any)
+	if !ok || auth["user"] != "admin" {
+		t.Errorf("expected database.auth.user='admin', got %v", db["auth"])
+	}
+}
+
+func TestFile_Read_IncludeCycle(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"a.yaml": "b: !include b.yaml\n",
+		"b.yaml": "a: !include a.yaml\n",
+	})
+
+	_, err := Provider(filepath.Join(tmpDir, "a.yaml")).Read()
+
+	if err == nil {
+		t.Fatal("expected error for include cycle")
+	}
+
+	a, b := filepath.Join(tmpDir, "a.yaml"), filepath.Join(tmpDir, "b.yaml")
+	chain := a + " -> " + b + " -> " + a
+	if !strings.Contains(err.Error(), chain) {
+		t.Errorf("expected error to contain %q, got %q", chain, err.Error())
+	}
+}
+
+func TestFile_Read_Extends(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"base/base.yaml": "name: base\ndatabase:\n  host: localhost\n  port: 5432\n",
+		"config.yaml":    "extends: base/base.yaml\ndatabase:\n  port: 6543\n",
+	})
+
+	m, err := Provider(filepath.Join(tmpDir, "config.yaml")).Read()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := m["extends"]; ok {
+		t.Error("extends key should be removed")
+	}
+	if m["name"] != "base" {
+		t.Errorf("expected name='base', got %v", m["name"])
+	}
+	db := m["database"].(map[string]any)
+	if db["host"] != "localhost" {
+		t.Errorf("expected database.host='localhost', got %v", db["host"])
+	}
+	if db["port"] != 6543 {
+		t.Errorf("expected database.port=6543, got %v", db["port"])
+	}
+}
+
+func TestFile_Read_ExtendsCycle(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"a.yaml": "extends: a.yaml\n",
+	})
+
+	_, err := Provider(filepath.Join(tmpDir, "a.yaml")).Read()
+
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Fatalf("expected include cycle error, got %v", err)
+	}
+}
+
+func TestFile_Read_MergeKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "base: &base\n  host: localhost\n  port: 5432\nprimary:\n  <<: *base\n  port: 6543\n",
+	})
+
+	m, err := Provider(filepath.Join(tmpDir, "config.yaml")).Read()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	primary := m["primary"].(map[string]any)
+	if primary["host"] != "localhost" || primary["port"] != 6543 {
+		t.Errorf("expected primary={host:localhost port:6543}, got %v", primary)
+	}
+}
+
+func TestFile_Read_AliasContainsItself(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "a: &x\n  b: *x\n",
+	})
+
+	_, err := Provider(filepath.Join(tmpDir, "config.yaml")).Read()
+
+	if err == nil || !strings.Contains(err.Error(), `anchor "x" contains itself`) {
+		t.Fatalf("expected anchor error, got %v", err)
+	}
+}
+
+func TestFile_Read_ExcessiveAliasing(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("a0: &a0 [x, x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i < 9; i++ {
+		p := fmt.Sprintf("*a%d", i-1)
+		fmt.Fprintf(&b, "a%d: &a%d [%s]\n", i, i, strings.Repeat(p+", ", 9)+p)
+	}
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{"config.yaml": b.String()})
+
+	_, err := Provider(filepath.Join(tmpDir, "config.yaml")).Read()
+
+	if err == nil || !strings.Contains(err.Error(), "excessive aliasing") {
+		t.Fatalf("expected excessive aliasing error, got %v", err)
+	}
+}
+
+func TestLoadPath_Include(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "extends: base.yaml\ndatabase: !include db.yaml\n",
+		"base.yaml":   "name: base\n",
+		"db.yaml":     "host: dbhost\n",
+	})
+
+	type Config struct {
+		Name     string `yaml:"name"`
+		Database struct {
+			Host string `yaml:"host"`
+		} `yaml:"database"`
+	}
+
+	var conf Config
+	err := LoadPath("TEST_", filepath.Join(tmpDir, "config.yaml"), &conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "base" {
+		t.Errorf("expected Name='base', got %q", conf.Name)
+	}
+
+	if conf.Database.Host != "dbhost" {
+		t.Errorf("expected Database.Host='dbhost', got %q", conf.Database.Host)
+	}
+}
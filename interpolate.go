@@ -0,0 +1,212 @@
+package mykonf
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// interpolator resolves ${key} references between the values of a
+// LoadContext, e.g. dsn: postgres://${database.user}@${database.host}.
+// References must contain the delimiter, since ${NAME} refers to an
+// environment variable. A leading delimiter, as in ${.name}, marks an
+// explicit reference and is how top-level keys are referenced. $${key}
+// is a literal ${key}.
+type interpolator struct {
+	c     *LoadContext
+	all   map[string]any
+	keys  []string
+	done  map[string]any
+	stack []string
+}
+
+// interpolate resolves the references in the string values, and the
+// strings in lists, of the keys that bind to fields and were set by files
+// or default tags, once all sources and defaults are merged. Values of
+// fields tagged expand:"false" and secret values are left as is.
+func (c *LoadContext) interpolate() error {
+	in := &interpolator{
+		c:    c,
+		all:  c.Koanf.All(),
+		keys: c.Koanf.Keys(),
+		done: make(map[string]any),
+	}
+	slices.Sort(in.keys)
+
+	var errs []error
+	for _, key := range in.keys {
+		if !hasRefs(in.all[key]) || !in.templated(key) {
+			continue
+		}
+
+		v, err := in.resolve(key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		err = c.Koanf.Set(key, v)
+		if err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hasRefs reports whether v, or a string in it, may hold a reference.
+func hasRefs(v any) bool {
+	switch v := v.(type) {
+	case string:
+		return strings.Contains(v, "${")
+	case []any:
+		return slices.ContainsFunc(v, hasRefs)
+	case map[string]any:
+		for _, sub := range v {
+			if hasRefs(sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// templated reports whether the references in the value of key are
+// resolved: key binds to a field and the value in effect was set by a
+// file or a default tag, not by the environment, flags or a secret.
+func (in *interpolator) templated(key string) bool {
+	c := in.c
+	if !c.fieldKeys().has(key, c.Loader.delim) || c.noExpand(key) {
+		return false
+	}
+	o := c.origin(key)
+	return (o.Kind == OriginFile || o.Kind == OriginDefault) && !o.Secret
+}
+
+// resolve returns the value of key with its references resolved.
+func (in *interpolator) resolve(key string) (any, error) {
+	if v, ok := in.done[key]; ok {
+		return v, nil
+	}
+
+	if i := slices.Index(in.stack, key); i >= 0 {
+		chain := append(slices.Clone(in.stack[i:]), key)
+		return nil, fmt.Errorf("reference cycle: %s", strings.Join(chain, " -> "))
+	}
+
+	raw, ok := in.all[key]
+	if !ok {
+		msg := fmt.Sprintf("unknown key %q", key)
+		if s := closest(key, in.keys); s != "" {
+			msg += fmt.Sprintf(", did you mean %q?", s)
+		}
+		return nil, errors.New(msg)
+	}
+	if !in.templated(key) {
+		return raw, nil
+	}
+
+	in.stack = append(in.stack, key)
+	defer func() { in.stack = in.stack[:len(in.stack)-1] }()
+
+	v, err := in.resolveValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	in.done[key] = v
+	return v, nil
+}
+
+// resolveValue resolves the references in v, a string or the items of a
+// list, and returns a copy.
+func (in *interpolator) resolveValue(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return in.resolveString(v)
+
+	case []any:
+		out := make([]any, len(v))
+		for i, sub := range v {
+			var err error
+			out[i], err = in.resolveValue(sub)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+
+	case map[string]any:
+		// items of lists of structs
+		out := make(map[string]any, len(v))
+		for k, sub := range v {
+			var err error
+			out[k], err = in.resolveValue(sub)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// resolveString replaces the references in s with the values they
+// reference.
+func (in *interpolator) resolveString(s string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			break
+		}
+		j += i
+
+		ref, ok := in.refKey(s[i+2 : j])
+		if !ok {
+			b.WriteString(s[:i+2])
+			s = s[i+2:]
+			continue
+		}
+		if i > 0 && s[i-1] == '$' {
+			// $${key} is a literal ${key}
+			b.WriteString(s[:i-1])
+			b.WriteString(s[i : j+1])
+			s = s[j+1:]
+			continue
+		}
+
+		v, err := in.resolve(ref)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:i])
+		b.WriteString(fmt.Sprint(v))
+		s = s[j+1:]
+	}
+	b.WriteString(s)
+	return b.String(), nil
+}
+
+// refKey returns the key path referenced by ref, the body of ${...}.
+// It reports false if ref is not a reference.
+func (in *interpolator) refKey(ref string) (string, bool) {
+	delim := in.c.Loader.delim
+	key, explicit := strings.CutPrefix(ref, delim)
+	if !explicit && !strings.Contains(ref, delim) {
+		return "", false
+	}
+	for _, part := range strings.Split(key, delim) {
+		if part == "" {
+			return "", false
+		}
+		for i := range len(part) {
+			if c := part[i]; !isNameChar(c) && c != '-' {
+				return "", false
+			}
+		}
+	}
+	return key, true
+}
@@ -0,0 +1,149 @@
+package mykonf
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type interpolateConfig struct {
+	DSN      string `yaml:"dsn"`
+	Template string `yaml:"template" expand:"false"`
+	Database struct {
+		User string `yaml:"user" default:"postgres"`
+		Host string `yaml:"host"`
+		Port int    `yaml:"port" default:"5432"`
+	} `yaml:"database"`
+}
+
+func TestLoader_Interpolate(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "dsn: postgres://${database.user}@${database.host}:${database.port}/app\n" +
+			"template: ${database.host}\n" +
+			"database:\n  host: localhost\n",
+	})
+
+	t.Setenv("INTERP_DATABASE_HOST", "db.prod")
+
+	var conf interpolateConfig
+	err := New("INTERP_", WithSources(FileSource(path), EnvSource("INTERP_"))).Load(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "postgres://postgres@db.prod:5432/app"
+	if conf.DSN != expected {
+		t.Errorf("expected DSN=%q, got %q", expected, conf.DSN)
+	}
+	if conf.Template != "${database.host}" {
+		t.Errorf("expected Template=${database.host}, got %q", conf.Template)
+	}
+}
+
+func TestLoader_InterpolateErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "dsn: ${database.hots}\n" +
+			"database:\n  user: ${database.host}\n  host: ${database.user}\n",
+	})
+
+	var conf interpolateConfig
+	err := New("INTERP_", WithSources(FileSource(path))).Load(&conf)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	msg := err.Error()
+	expected := []string{
+		`dsn: unknown key "database.hots", did you mean "database.host"?`,
+		"database.host: reference cycle: database.host -> database.user -> database.host",
+	}
+	for _, e := range expected {
+		if !strings.Contains(msg, e) {
+			t.Errorf("expected error to contain %q, got:\n%s", e, msg)
+		}
+	}
+}
+
+func TestLoader_InterpolateTopLevel(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "name: api.local\n" +
+			"dsn: http://${.name}/x\n" +
+			"template: ${.name}\n" +
+			"database:\n  host: ${.database.user}-${EXP_HOST:-db}\n",
+	})
+
+	for _, policy := range []ExpandPolicy{ExpandEnv, ExpandStrict} {
+		var conf struct {
+			Name     string `yaml:"name"`
+			DSN      string `yaml:"dsn"`
+			Template string `yaml:"template" expand:"false"`
+			Database struct {
+				User string `yaml:"user" default:"postgres"`
+				Host string `yaml:"host"`
+			} `yaml:"database"`
+		}
+		err := New("INTERP_", WithSources(FileSource(path)), WithExpand(policy)).Load(&conf)
+		if err != nil {
+			t.Fatalf("policy %d: unexpected error: %v", policy, err)
+		}
+
+		if conf.DSN != "http://api.local/x" {
+			t.Errorf("policy %d: expected DSN=http://api.local/x, got %q", policy, conf.DSN)
+		}
+		if conf.Template != "${.name}" {
+			t.Errorf("policy %d: expected Template=${.name}, got %q", policy, conf.Template)
+		}
+		if conf.Database.Host != "postgres-db" {
+			t.Errorf("policy %d: expected Database.Host=postgres-db, got %q", policy, conf.Database.Host)
+		}
+	}
+}
+
+func TestLoader_InterpolateScope(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "name: api.local\n" +
+			"dsn: $${.name} $${database.user}\n" +
+			"hosts: ['${.name}', 'x-${database.user}']\n",
+	})
+
+	// env values, even of unknown vars or secret fields, are never
+	// interpolated
+	t.Setenv("SOME_UNRELATED", "echo ${foo.bar}")
+	t.Setenv("PASS", "a${b.c}d")
+
+	type Config struct {
+		Name     string   `yaml:"name"`
+		DSN      string   `yaml:"dsn"`
+		Pass     Secret   `yaml:"pass"`
+		Hosts    []string `yaml:"hosts"`
+		Database struct {
+			User string `yaml:"user" default:"postgres"`
+		} `yaml:"database"`
+	}
+
+	for _, policy := range []ExpandPolicy{ExpandEnv, ExpandNone} {
+		var conf Config
+		err := New("", WithSources(FileSource(path), EnvSource("")), WithExpand(policy)).Load(&conf)
+		if err != nil {
+			t.Fatalf("policy %d: unexpected error: %v", policy, err)
+		}
+
+		if conf.DSN != "${.name} ${database.user}" {
+			t.Errorf("policy %d: expected escaped DSN=${.name} ${database.user}, got %q", policy, conf.DSN)
+		}
+		if conf.Pass.Reveal() != "a${b.c}d" {
+			t.Errorf("policy %d: expected Pass from env as is, got %q", policy, conf.Pass.Reveal())
+		}
+		if len(conf.Hosts) != 2 || conf.Hosts[0] != "api.local" || conf.Hosts[1] != "x-postgres" {
+			t.Errorf("policy %d: expected Hosts=[api.local x-postgres], got %q", policy, conf.Hosts)
+		}
+	}
+}
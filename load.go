@@ -2,77 +2,51 @@ package mykonf
 
 import (
 	"encoding/json"
+	"maps"
 	"os"
+	"path/filepath"
 	"reflect"
-	"strings"
 
 	"github.com/creasty/defaults"
 	"github.com/go-viper/mapstructure/v2"
-	"github.com/knadh/koanf/parsers/yaml"
-	"github.com/knadh/koanf/providers/env/v2"
-	"github.com/knadh/koanf/v2"
 )
 
-// Load does:
-// 1. load yaml
-// 2. set with env
-// 3. load defaults
+// Load loads conf from the config files named by ConfigPaths(envPrefix)
+// and the env vars starting with envPrefix. It is New(envPrefix).Load,
+// see Loader.Load for the steps.
 func Load(envPrefix string, conf any) error {
-	return LoadPath(envPrefix, ConfigPath(envPrefix), conf)
+	return New(envPrefix).Load(conf)
 }
 
-// LoadPath does:
-// 1. load yaml
-// 2. set with env
-// 3. load defaults
-func LoadPath(envPrefix, path string, conf any) error {
-	k := koanf.New(".")
-
-	_, err := os.Stat(path)
-	if err == nil || os.IsExist(err) {
-		err = k.Load(Provider(path), yaml.Parser())
-		if err != nil {
-			return err
-		}
-	}
-
-	envToKey := EnvToKey(conf, "yaml")
-	err = k.Load(env.Provider(".", env.Opt{
-		Prefix: envPrefix,
-		TransformFunc: func(k, v string) (string, any) {
-			e := strings.TrimPrefix(k, envPrefix)
-			key, ok := envToKey[e]
-			if !ok {
-				return strings.ToLower(e), v
-			}
-			return key, v
-		},
-	}), nil)
+// LoadAs is Load into a new T.
+func LoadAs[T any](envPrefix string) (*T, error) {
+	conf := new(T)
+	err := Load(envPrefix, conf)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return conf, nil
+}
 
-	err = k.UnmarshalWithConf("", conf, koanf.UnmarshalConf{Tag: "yaml",
-		DecoderConfig: &mapstructure.DecoderConfig{
-			DecodeHook: mapstructure.ComposeDecodeHookFunc(
-				StringToJsonHookFunc(),
-				mapstructure.StringToSliceHookFunc(","),
-				mapstructure.StringToTimeDurationHookFunc(),
-				mapstructure.TextUnmarshallerHookFunc(),
-			),
-			Metadata:         nil,
-			WeaklyTypedInput: true,
-		}})
-	if err != nil {
-		return err
-	}
+// LoadPath is Load reading the config file at path instead, see
+// Loader.Load for the steps.
+func LoadPath(envPrefix, path string, conf any) error {
+	return New(envPrefix, WithSources(FileSource(path), EnvSource(envPrefix))).Load(conf)
+}
 
-	return defaults.Set(conf)
+// Check reports the fields of conf that share an env name, naming both
+// key paths, as Load does before loading.
+func Check(conf any) error {
+	return New("").Check(conf)
 }
 
 const defaultConfigEnv = "SERVER_CONFIG"
 const defaultConfigPath = "config.yaml"
 
+// controlEnvs are prefixed env names read by mykonf itself rather than
+// bound to fields.
+var controlEnvs = []string{defaultConfigEnv, ageKeyFileEnv}
+
 func ConfigPath(envPrefix string) string {
 	p := os.Getenv(envPrefix + defaultConfigEnv)
 	if p != "" {
@@ -81,6 +55,69 @@ func ConfigPath(envPrefix string) string {
 	return defaultConfigPath
 }
 
+// ConfigPaths splits ConfigPath by os.PathListSeparator, so
+// {envPrefix}SERVER_CONFIG may list several layered files.
+func ConfigPaths(envPrefix string) []string {
+	var paths []string
+	for _, p := range filepath.SplitList(ConfigPath(envPrefix)) {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// DefaultDecodeHooks returns the decode hook chain used when no
+// WithDecodeHooks option is given.
+func DefaultDecodeHooks() []mapstructure.DecodeHookFunc {
+	return []mapstructure.DecodeHookFunc{
+		StringToSecretBytesHookFunc(),
+		StringToJsonHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.TextUnmarshallerHookFunc(),
+	}
+}
+
+// embeddedHookFunc decodes the embedded structs without a tag name from
+// the keys of their parent, like walkFields flattens them. The decoder
+// looks them up by field name, as it only squashes them all or none.
+func embeddedHookFunc(tag string) mapstructure.DecodeHookFuncType {
+	return func(_ reflect.Type, to reflect.Type, data any) (any, error) {
+		m, ok := data.(map[string]any)
+		if !ok || to.Kind() != reflect.Struct {
+			return data, nil
+		}
+
+		var out map[string]any
+		for i := range to.NumField() {
+			field := to.Field(i)
+			if !field.IsExported() || !isEmbedded(field, tag) || !isInline(field, tag) {
+				continue
+			}
+			if out == nil {
+				out = maps.Clone(m)
+			}
+			out[field.Name] = m
+		}
+		if out == nil {
+			return data, nil
+		}
+		return out, nil
+	}
+}
+
+// StringToSecretBytesHookFunc decodes strings into SecretBytes as is. It
+// must come before StringToSliceHookFunc, which would split them.
+func StringToSecretBytesHookFunc() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data any) (any, error) {
+		if f.Kind() != reflect.String || t != secretBytesType {
+			return data, nil
+		}
+		return SecretBytes(data.(string)), nil
+	}
+}
+
 func StringToJsonHookFunc() mapstructure.DecodeHookFunc {
 	return func(f reflect.Type, t reflect.Type, data any) (any, error) {
 		if f.Kind() != reflect.String {
@@ -99,6 +136,13 @@ func StringToJsonHookFunc() mapstructure.DecodeHookFunc {
 		}
 
 		r := reflect.New(t)
+		if t.Kind() == reflect.Struct {
+			// fields missing from the json keep their defaults
+			err := defaults.Set(r.Interface())
+			if err != nil {
+				return nil, err
+			}
+		}
 		err := json.Unmarshal([]byte(v), r.Interface())
 		if err != nil {
 			return nil, err
@@ -541,3 +541,55 @@ func TestLoadPath_EmptyFile(t *testing.T) {
 		t.Errorf("expected Name='default', got %q", conf.Name)
 	}
 }
+
+func TestConfigPaths_List(t *testing.T) {
+	list := "/etc/app/config.yaml" + string(os.PathListSeparator) + "/etc/app/config.prod.yaml"
+	t.Setenv("TEST_SERVER_CONFIG", list)
+
+	paths := ConfigPaths("TEST_")
+
+	expected := []string{"/etc/app/config.yaml", "/etc/app/config.prod.yaml"}
+	if len(paths) != len(expected) {
+		t.Fatalf("expected %d paths, got %d: %v", len(expected), len(paths), paths)
+	}
+	for i, p := range expected {
+		if paths[i] != p {
+			t.Errorf("expected paths[%d]=%q, got %q", i, p, paths[i])
+		}
+	}
+}
+
+func TestLoad_LayeredConfigPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := filepath.Join(tmpDir, "config.yaml")
+	overlay := filepath.Join(tmpDir, "config.prod.yaml")
+
+	if err := os.WriteFile(base, []byte("name: base\nport: 8080\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(overlay, []byte("port: 9090\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	t.Setenv("LAYER_SERVER_CONFIG", base+string(os.PathListSeparator)+overlay)
+
+	type Config struct {
+		Name string `yaml:"name"`
+		Port int    `yaml:"port"`
+	}
+
+	var conf Config
+	err := Load("LAYER_", &conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "base" {
+		t.Errorf("expected Name='base', got %q", conf.Name)
+	}
+
+	if conf.Port != 9090 {
+		t.Errorf("expected Port=9090, got %d", conf.Port)
+	}
+}
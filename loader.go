@@ -0,0 +1,307 @@
+package mykonf
+
+import (
+	"log"
+	"slices"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/knadh/koanf/v2"
+)
+
+// ExpandPolicy controls how environment variables referenced in config
+// files are expanded.
+type ExpandPolicy int
+
+const (
+	// ExpandEnv replaces $VAR and ${VAR} with the process environment,
+	// unset variables become empty strings. Shell-style modifiers are
+	// supported: ${VAR:-default}, ${VAR:+alt}, ${VAR:?message} failing if
+	// VAR is unset or empty, and $$ for a literal $.
+	ExpandEnv ExpandPolicy = iota
+	// ExpandNone leaves file contents untouched.
+	ExpandNone
+	// ExpandStrict is ExpandEnv that fails listing every unset variable
+	// referenced without a default.
+	ExpandStrict
+)
+
+// UnusedEnvPolicy controls what happens to prefixed environment
+// variables that bind to no field.
+type UnusedEnvPolicy int
+
+const (
+	// UnusedEnvIgnore drops them silently.
+	UnusedEnvIgnore UnusedEnvPolicy = iota
+	// UnusedEnvWarn logs them.
+	UnusedEnvWarn
+	// UnusedEnvError fails loading.
+	UnusedEnvError
+)
+
+// Loader loads configuration from an ordered list of sources into a
+// struct. Later sources override earlier ones, and default tags fill the
+// keys that no source provided.
+type Loader struct {
+	envPrefix          string
+	sources            []Source
+	tag                string
+	delim              string
+	hooks              []mapstructure.DecodeHookFunc
+	strict             bool
+	expand             ExpandPolicy
+	expandPrefixes     []string
+	format             Format
+	flags              *Flags
+	unusedEnv          UnusedEnvPolicy
+	sliceEnv           SliceEnvPolicy
+	preserveMapKeyCase bool
+	validate           bool
+}
+
+// Option configures a Loader.
+type Option func(*Loader)
+
+// WithSources replaces the default sources, which are the files from
+// ConfigPaths followed by the prefixed environment, and the flags if
+// BindFlags was called.
+func WithSources(sources ...Source) Option {
+	return func(l *Loader) {
+		l.sources = sources
+	}
+}
+
+// WithTag sets the struct tag used for key names, "yaml" by default.
+func WithTag(tag string) Option {
+	return func(l *Loader) {
+		l.tag = tag
+	}
+}
+
+// WithDelim sets the key path delimiter, "." by default.
+func WithDelim(delim string) Option {
+	return func(l *Loader) {
+		l.delim = delim
+	}
+}
+
+// WithDecodeHooks replaces the decode hook chain. Use DefaultDecodeHooks
+// to extend the default chain instead.
+func WithDecodeHooks(hooks ...mapstructure.DecodeHookFunc) Option {
+	return func(l *Loader) {
+		l.hooks = hooks
+	}
+}
+
+// WithStrict makes loading fail when a config file or other non-env
+// source provides keys that do not map to any field. Every unknown key is
+// reported with its file and the closest valid key.
+func WithStrict(strict bool) Option {
+	return func(l *Loader) {
+		l.strict = strict
+	}
+}
+
+// WithUnusedEnv sets how prefixed env vars, including dotenv ones, that
+// bind to no field are reported. Each is listed with the closest valid
+// name, e.g. APP_DATABSE_HOST, did you mean APP_DATABASE_HOST?
+func WithUnusedEnv(policy UnusedEnvPolicy) Option {
+	return func(l *Loader) {
+		l.unusedEnv = policy
+	}
+}
+
+// WithSliceEnv sets how indexed env vars, such as APP_UPSTREAMS_0_HOST,
+// combine with the items loaded by earlier sources, SliceEnvMerge by
+// default.
+func WithSliceEnv(policy SliceEnvPolicy) Option {
+	return func(l *Loader) {
+		l.sliceEnv = policy
+	}
+}
+
+// WithPreserveMapKeyCase keeps the case of map keys in env names, such
+// as Primary in APP_DATABASES_Primary_HOST, rather than lowercasing them.
+func WithPreserveMapKeyCase(preserve bool) Option {
+	return func(l *Loader) {
+		l.preserveMapKeyCase = preserve
+	}
+}
+
+// WithValidate turns the validation stage on or off, it is on by
+// default. It checks validate tags, such as validate:"required,max=65535",
+// and calls Validate on every struct implementing Validator.
+func WithValidate(validate bool) Option {
+	return func(l *Loader) {
+		l.validate = validate
+	}
+}
+
+// WithExpand sets how environment variables in config files are expanded.
+func WithExpand(policy ExpandPolicy) Option {
+	return func(l *Loader) {
+		l.expand = policy
+	}
+}
+
+// WithExpandPrefixes only expands the environment variables starting
+// with one of prefixes in config files, e.g. "APP_", leaving other
+// references as is.
+func WithExpandPrefixes(prefixes ...string) Option {
+	return func(l *Loader) {
+		l.expandPrefixes = prefixes
+	}
+}
+
+// WithFormat forces the format of config files, which is otherwise
+// detected from the extension. Layer.Format still takes precedence.
+func WithFormat(format Format) Option {
+	return func(l *Loader) {
+		l.format = format
+	}
+}
+
+// New returns a Loader for envPrefix. Without options it behaves like
+// Load.
+func New(envPrefix string, opts ...Option) *Loader {
+	l := &Loader{
+		envPrefix: envPrefix,
+		tag:       "yaml",
+		delim:     ".",
+		hooks:     DefaultDecodeHooks(),
+		expand:    ExpandEnv,
+		validate:  true,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// EnvPrefix returns the prefix the Loader was created with.
+func (l *Loader) EnvPrefix() string { return l.envPrefix }
+
+// Tag returns the struct tag used for key names.
+func (l *Loader) Tag() string { return l.tag }
+
+// Delim returns the key path delimiter.
+func (l *Loader) Delim() string { return l.delim }
+
+// Sources returns the sources in load order.
+func (l *Loader) Sources() []Source {
+	if l.sources != nil {
+		return l.sources
+	}
+	var paths []string
+	if l.flags != nil {
+		paths = l.flags.ConfigPaths()
+	}
+	if paths == nil {
+		paths = ConfigPaths(l.envPrefix)
+	}
+
+	var layers []Layer
+	for _, path := range paths {
+		layers = append(layers, Optional(path))
+	}
+	sources := []Source{
+		FilesSource(layers...),
+		EnvSource(l.envPrefix),
+	}
+	if l.flags != nil {
+		sources = append(sources, FlagSource(l.flags))
+	}
+	return sources
+}
+
+// Check reports the fields of conf that share an env name with the
+// Loader's tag and delimiter, e.g. base_home.dir and base.home_dir both
+// read BASE_HOME_DIR.
+func (l *Loader) Check(conf any) error {
+	return checkEnvNames(conf, l.tag, l.delim)
+}
+
+// Load does:
+// 1. check that no two fields of conf share an env name
+// 2. load every source in order, expanding and decrypting file values,
+// failing with a *LoadError that lists every file or variable that could
+// not be read
+// 3. with WithStrict, fail on keys that bind to no field, and report
+// unused env vars as set by WithUnusedEnv
+// 4. load defaults for keys not provided
+// 5. resolve ${key} references between values
+// 6. decode the merged keys into conf, failing with a *LoadError that
+// lists every value that could not be decoded, then run SetDefaults
+// hooks
+// 7. validate conf, unless disabled by WithValidate
+func (l *Loader) Load(conf any) error {
+	_, err := l.load(conf)
+	return err
+}
+
+// load is Load that also returns the context, which is partially filled
+// when loading fails.
+func (l *Loader) load(conf any) (*LoadContext, error) {
+	c := newLoadContext(l, conf)
+
+	err := l.Check(conf)
+	if err != nil {
+		return c, err
+	}
+
+	var problems []Problem
+	for _, s := range l.Sources() {
+		err := s.Load(c)
+		if err != nil {
+			problems = append(problems, sourceProblems(err)...)
+		}
+	}
+	if len(problems) > 0 {
+		return c, &LoadError{Problems: problems}
+	}
+
+	if l.strict {
+		err := c.checkUnknown()
+		if err != nil {
+			return c, err
+		}
+	}
+
+	if l.unusedEnv != UnusedEnvIgnore {
+		err := c.checkUnusedEnv()
+		if err != nil {
+			if l.unusedEnv == UnusedEnvError {
+				return c, err
+			}
+			log.Printf("mykonf: %v", err)
+		}
+	}
+
+	err = c.loadDefaults()
+	if err != nil {
+		return c, err
+	}
+
+	err = c.interpolate()
+	if err != nil {
+		return c, err
+	}
+
+	err = c.Koanf.UnmarshalWithConf("", conf, koanf.UnmarshalConf{Tag: l.tag,
+		DecoderConfig: &mapstructure.DecoderConfig{
+			// flatten like walkFields, embedded structs with a tag name
+			// are not
+			DecodeHook:       mapstructure.ComposeDecodeHookFunc(append(slices.Clone(l.hooks), embeddedHookFunc(l.tag))...),
+			Metadata:         nil,
+			WeaklyTypedInput: true,
+			SquashTagOption:  squashOption(l.tag),
+		}})
+	if err != nil {
+		return c, c.loadError(err)
+	}
+	c.callSetters()
+
+	if l.validate {
+		return c, c.validate()
+	}
+	return c, nil
+}
@@ -0,0 +1,253 @@
+package mykonf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+func TestLoader_DefaultSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "config.yaml")
+
+	content := []byte("name: from_file\nport: 8080\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	t.Setenv("LDR_SERVER_CONFIG", tmpFile)
+	t.Setenv("LDR_PORT", "9090")
+
+	type Config struct {
+		Name string `yaml:"name"`
+		Port int    `yaml:"port"`
+	}
+
+	var conf Config
+	err := New("LDR_").Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "from_file" {
+		t.Errorf("expected Name='from_file', got %q", conf.Name)
+	}
+
+	if conf.Port != 9090 {
+		t.Errorf("expected Port=9090, got %d", conf.Port)
+	}
+}
+
+func TestLoader_WithTag(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "config.yaml")
+
+	content := []byte("server_name: tagged\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	t.Setenv("TAG_SERVER_PORT", "7070")
+
+	type Config struct {
+		Name string `koanf:"server_name"`
+		Port int    `koanf:"server_port"`
+	}
+
+	var conf Config
+	err := New("TAG_",
+		WithSources(FileSource(tmpFile), EnvSource("TAG_")),
+		WithTag("koanf"),
+	).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "tagged" {
+		t.Errorf("expected Name='tagged', got %q", conf.Name)
+	}
+
+	if conf.Port != 7070 {
+		t.Errorf("expected Port=7070, got %d", conf.Port)
+	}
+}
+
+func TestLoader_WithDelim(t *testing.T) {
+	t.Setenv("DELIM_DATABASE_HOST", "dbhost")
+
+	type Config struct {
+		Database struct {
+			Host string `yaml:"host"`
+		} `yaml:"database"`
+	}
+
+	var conf Config
+	err := New("DELIM_",
+		WithSources(EnvSource("DELIM_")),
+		WithDelim("/"),
+	).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Database.Host != "dbhost" {
+		t.Errorf("expected Database.Host='dbhost', got %q", conf.Database.Host)
+	}
+}
+
+func TestLoader_WithStrict(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "config.yaml")
+
+	content := []byte("name: app\nnmae: typo\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	type Config struct {
+		Name string `yaml:"name"`
+	}
+
+	var conf Config
+	err := New("TEST_", WithSources(FileSource(tmpFile)), WithStrict(true)).Load(&conf)
+
+	if err == nil {
+		t.Fatal("expected error for unknown key in strict mode")
+	}
+}
+
+func TestLoader_WithExpandNone(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "config.yaml")
+
+	t.Setenv("MY_SECRET", "supersecret")
+
+	content := []byte("password: $MY_SECRET\n")
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	type Config struct {
+		Password string `yaml:"password"`
+	}
+
+	var conf Config
+	err := New("TEST_", WithSources(FileSource(tmpFile)), WithExpand(ExpandNone)).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Password != "$MY_SECRET" {
+		t.Errorf("expected Password='$MY_SECRET', got %q", conf.Password)
+	}
+}
+
+func TestLoader_WithDecodeHooks(t *testing.T) {
+	t.Setenv("HOOK_TAGS", "a;b")
+
+	type Config struct {
+		Tags []string `yaml:"tags"`
+	}
+
+	var conf Config
+	err := New("HOOK_",
+		WithSources(EnvSource("HOOK_")),
+		WithDecodeHooks(mapstructure.StringToSliceHookFunc(";")),
+	).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(conf.Tags) != 2 || conf.Tags[0] != "a" || conf.Tags[1] != "b" {
+		t.Errorf("expected Tags=[a b], got %v", conf.Tags)
+	}
+}
+
+func TestLoader_Inline(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "name: app\nlevel: trace\n",
+	})
+
+	t.Setenv("INLINE_HOME", "/data")
+
+	type Base struct {
+		Home  string `yaml:"home" default:"/srv"`
+		Port  int    `yaml:"port" default:"8080"`
+		Level string `yaml:"level" validate:"oneof=debug info"`
+	}
+	type Config struct {
+		Base
+		Name string `yaml:"name"`
+	}
+
+	var conf Config
+	err := New("INLINE_", WithSources(FileSource(path), EnvSource("INLINE_")), WithStrict(true)).Load(&conf)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if len(verr.Fields) != 1 || verr.Fields[0].Key != "level" || verr.Fields[0].Env != "INLINE_LEVEL" {
+		t.Errorf("expected one level (INLINE_LEVEL) error, got %v", verr.Fields)
+	}
+
+	if conf.Name != "app" {
+		t.Errorf("expected Name=app, got %q", conf.Name)
+	}
+	if conf.Home != "/data" {
+		t.Errorf("expected Home=/data, got %q", conf.Home)
+	}
+	if conf.Port != 8080 {
+		t.Errorf("expected Port=8080, got %d", conf.Port)
+	}
+}
+
+func TestLoader_EmbeddedTagged(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "home: /srv\nlog:\n  level: debug\nport: x\n",
+	})
+
+	t.Setenv("EMBED_LOG_LEVEL", "trace")
+
+	type Base struct {
+		Home string `yaml:"home"`
+		Port int    `yaml:"port"`
+	}
+	type Log struct {
+		Level string `yaml:"level"`
+	}
+	type Config struct {
+		Base
+		Log `yaml:"log"`
+	}
+
+	var conf Config
+	err := New("EMBED_", WithSources(FileSource(path), EnvSource("EMBED_")), WithStrict(true)).Load(&conf)
+
+	var lerr *LoadError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("expected *LoadError, got %v", err)
+	}
+	if len(lerr.Problems) != 1 || lerr.Problems[0].Key != "port" || lerr.Problems[0].Source.Line != 4 {
+		t.Errorf("expected one problem at port, line 4, got %v", lerr.Problems)
+	}
+
+	if conf.Home != "/srv" {
+		t.Errorf("expected Home=/srv, got %q", conf.Home)
+	}
+	if conf.Log.Level != "trace" {
+		t.Errorf("expected Log.Level=trace, got %q", conf.Log.Level)
+	}
+}
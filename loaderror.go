@@ -0,0 +1,277 @@
+package mykonf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// redacted replaces the values of secret fields in errors.
+const redacted = "[redacted]"
+
+// Problem is a value that could not be decoded into its field, or a
+// source that could not be read, which has no Key.
+type Problem struct {
+	// Key is the key path, e.g. database.port.
+	Key string
+	// Source is the source that set the value, e.g. env APP_PORT or
+	// file config.yaml:3:1. Its Value is redacted for secret fields.
+	Source Origin
+	// Expected is the Go type of the field, e.g. int.
+	Expected string
+	// Value is the offending value, redacted for secret fields.
+	Value string
+	Err   error
+}
+
+func (p Problem) Error() string {
+	var msg string
+	switch {
+	case p.Key != "":
+		msg = fmt.Sprintf("%s: cannot decode %s", p.Key, p.Value)
+		if p.Expected != "" {
+			msg += " as " + p.Expected
+		}
+		if p.Err != nil {
+			msg += ": " + p.Err.Error()
+		}
+	case p.Err != nil:
+		msg = p.Err.Error()
+	}
+	if p.Source.Kind != "" {
+		msg = p.Source.String() + ": " + msg
+	}
+	return msg
+}
+
+func (p Problem) Unwrap() error { return p.Err }
+
+// LoadError lists every source that could not be read, or every value
+// that could not be decoded.
+type LoadError struct {
+	Problems []Problem
+}
+
+func (e *LoadError) Error() string {
+	var b strings.Builder
+	b.WriteString("cannot load config:")
+	for _, p := range e.Problems {
+		b.WriteString("\n  " + p.Error())
+	}
+	return b.String()
+}
+
+// loadError turns a decode error into a LoadError, with one problem for
+// every field that failed. Errors it does not know are returned as is.
+func (c *LoadContext) loadError(err error) error {
+	var problems []Problem
+	var walk func(err error) bool
+	walk = func(err error) bool {
+		if de, ok := err.(*mapstructure.DecodeError); ok {
+			var inner *mapstructure.DecodeError
+			if !errors.As(de.Unwrap(), &inner) {
+				problems = append(problems, c.problem(de))
+				return true
+			}
+			err = de.Unwrap()
+		}
+
+		var errs []error
+		switch err := err.(type) {
+		case interface{ Unwrap() []error }:
+			errs = err.Unwrap()
+		case interface{ Unwrap() error }:
+			errs = []error{err.Unwrap()}
+		}
+		if len(errs) == 0 {
+			return false
+		}
+		for _, err := range errs {
+			if err != nil && !walk(err) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !walk(err) {
+		return err
+	}
+	return &LoadError{Problems: problems}
+}
+
+// sourceProblems returns the problems of err, returned by a source.
+// Errors that are not problems yet are problems of unknown origin.
+func sourceProblems(err error) []Problem {
+	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+		var problems []Problem
+		for _, err := range errs.Unwrap() {
+			problems = append(problems, sourceProblems(err)...)
+		}
+		return problems
+	}
+
+	var p Problem
+	if errors.As(err, &p) {
+		return []Problem{p}
+	}
+	return []Problem{{Err: err}}
+}
+
+// fileProblem returns err, met reading the file at path, as a problem
+// located in the file. b, if not nil, holds the contents err refers to.
+// Problems of included files are returned as is.
+func fileProblem(path string, b []byte, err error) error {
+	var p Problem
+	if errors.As(err, &p) {
+		return err
+	}
+
+	o := Origin{Kind: OriginFile, Name: path}
+	o.Line, o.Column = errorPosition(b, err)
+	return Problem{Source: o, Err: err}
+}
+
+// errorLine matches the positions in yaml and hcl errors, e.g.
+// "yaml: line 3: ..." and "At 3:5: ...".
+var errorLine = regexp.MustCompile(`\bline (\d+)|\bAt (\d+):(\d+)`)
+
+// errorPosition returns the line and column err refers to in b, 0 if
+// unknown.
+func errorPosition(b []byte, err error) (line, column int) {
+	var se *json.SyntaxError
+	var te *json.UnmarshalTypeError
+	var de *toml.DecodeError
+	switch {
+	case errors.As(err, &se) && b != nil:
+		return offsetPosition(b, se.Offset)
+	case errors.As(err, &te) && b != nil:
+		return offsetPosition(b, te.Offset)
+	case errors.As(err, &de):
+		return de.Position()
+	}
+
+	m := errorLine.FindStringSubmatch(err.Error())
+	switch {
+	case m == nil:
+		return 0, 0
+	case m[1] != "":
+		line, _ = strconv.Atoi(m[1])
+	default:
+		line, _ = strconv.Atoi(m[2])
+		column, _ = strconv.Atoi(m[3])
+	}
+	return line, column
+}
+
+// offsetPosition returns the line and column of the last byte read when
+// a json error occurred after reading offset bytes of b.
+func offsetPosition(b []byte, offset int64) (line, column int) {
+	offset = min(max(offset-1, 0), int64(len(b)))
+	before := b[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+func (c *LoadContext) problem(de *mapstructure.DecodeError) Problem {
+	delim := c.Loader.delim
+	p := Problem{Key: decodeKey(reflect.TypeOf(c.Conf), de.Name(), c.Loader.tag, delim), Err: de.Unwrap()}
+
+	// slice elements, e.g. hosts[0], are set by the key of the slice
+	key := p.Key
+	for strings.HasSuffix(key, "]") {
+		i := strings.LastIndex(key, "[")
+		if i < 0 {
+			break
+		}
+		key = key[:i]
+	}
+	p.Source = c.origin(key)
+
+	value := p.Source.Value
+	if t := c.fieldKeys().types[strings.ToLower(key)]; t != nil {
+		p.Expected = t.String()
+	}
+
+	var pe *mapstructure.ParseError
+	var ue *mapstructure.UnconvertibleTypeError
+	switch {
+	case errors.As(p.Err, &pe):
+		p.Expected = pe.Expected.Type().String()
+		value = pe.Value
+		p.Err = pe.Err
+	case errors.As(p.Err, &ue):
+		p.Expected = ue.Expected.Type().String()
+		value = ue.Value
+		p.Err = fmt.Errorf("unconvertible type %T", ue.Value)
+	}
+
+	if c.fieldKeys().isSecret(key, delim) || p.Source.Secret {
+		p.Value = redacted
+		p.Source.Value = redacted
+		// the error may quote the value
+		if s := fmt.Sprint(value); p.Err != nil && s != "" {
+			p.Err = errors.New(strings.ReplaceAll(p.Err.Error(), s, redacted))
+		}
+		return p
+	}
+
+	if s, ok := value.(string); ok {
+		p.Value = fmt.Sprintf("%q", s)
+	} else {
+		p.Value = fmt.Sprintf("%v", value)
+	}
+	return p
+}
+
+// decodeKey returns the key path of name, a field path in the decoder
+// errors of t, dropping the embedded structs the decoder names.
+func decodeKey(t reflect.Type, name, tag, delim string) string {
+	var parts []string
+	for _, part := range strings.Split(name, ".") {
+		seg, index, _ := strings.Cut(part, "[")
+		if index != "" {
+			index = "[" + index
+		}
+
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		var next reflect.Type
+		if t != nil && t.Kind() == reflect.Struct {
+			if f, ok := t.FieldByName(seg); ok && len(f.Index) == 1 && isEmbedded(f, tag) && isInline(f, tag) {
+				t = f.Type
+				continue
+			}
+			for i := range t.NumField() {
+				if n, ok := fieldName(t.Field(i), tag); ok && strings.EqualFold(n, seg) {
+					next = t.Field(i).Type
+					break
+				}
+			}
+		}
+		// every index steps into an item
+		for range strings.Count(index, "[") {
+			for next != nil && next.Kind() == reflect.Ptr {
+				next = next.Elem()
+			}
+			if next != nil && (next.Kind() == reflect.Slice || next.Kind() == reflect.Array || next.Kind() == reflect.Map) {
+				next = next.Elem()
+			} else {
+				next = nil
+			}
+		}
+		t = next
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, delim)
+}
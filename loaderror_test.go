@@ -0,0 +1,138 @@
+package mykonf
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type loadErrorConfig struct {
+	Port     int `yaml:"port"`
+	Database struct {
+		Port     int    `yaml:"port"`
+		Password int    `yaml:"password" secret:"true"`
+		Host     string `yaml:"host"`
+	} `yaml:"database"`
+}
+
+func TestLoader_LoadError(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "database:\n  port: fast\n  password: hunter2\n",
+	})
+
+	t.Setenv("LOADERR_PORT", "abc")
+
+	var conf loadErrorConfig
+	err := New("LOADERR_", WithSources(FileSource(path), EnvSource("LOADERR_"))).Load(&conf)
+
+	var le *LoadError
+	if !errors.As(err, &le) {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	if len(le.Problems) != 3 {
+		t.Fatalf("expected 3 problems, got %d:\n%v", len(le.Problems), err)
+	}
+
+	problems := make(map[string]Problem)
+	for _, p := range le.Problems {
+		problems[p.Key] = p
+	}
+
+	p := problems["port"]
+	if p.Source.Kind != OriginEnv || p.Source.Name != "LOADERR_PORT" {
+		t.Errorf("expected port from env LOADERR_PORT, got %v", p.Source)
+	}
+	if p.Expected != "int" {
+		t.Errorf("expected port Expected=int, got %q", p.Expected)
+	}
+	if p.Value != `"abc"` {
+		t.Errorf("expected port Value=\"abc\", got %q", p.Value)
+	}
+
+	p = problems["database.port"]
+	if p.Source.Name != path || p.Source.Line != 2 || p.Source.Column != 3 {
+		t.Errorf("expected database.port from %s:2:3, got %v", path, p.Source)
+	}
+
+	p = problems["database.password"]
+	if p.Value != redacted {
+		t.Errorf("expected database.password Value=%s, got %q", redacted, p.Value)
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("expected secret value to be redacted, got:\n%v", err)
+	}
+
+	msg := err.Error()
+	expected := []string{
+		`env LOADERR_PORT: port: cannot decode "abc" as int`,
+		"file " + path + `:2:3: database.port: cannot decode "fast" as int`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(msg, e) {
+			t.Errorf("expected error to contain %q, got:\n%s", e, msg)
+		}
+	}
+}
+
+func TestLoader_LoadErrorHook(t *testing.T) {
+	t.Setenv("LOADERR_HOOK_LABELS", "{bad")
+
+	var conf struct {
+		Labels map[string]string `yaml:"labels"`
+	}
+	err := New("LOADERR_HOOK_", WithSources(EnvSource("LOADERR_HOOK_"))).Load(&conf)
+
+	var le *LoadError
+	if !errors.As(err, &le) {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	p := le.Problems[0]
+	if p.Key != "labels" || p.Expected != "map[string]string" || p.Source.Name != "LOADERR_HOOK_LABELS" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+}
+
+func TestLoader_LoadErrorSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"c.yaml":  "port: 1\ndatabase: !include db.yaml\n",
+		"db.yaml": "host: [a, b\n",
+		"c.json":  "{\n  \"port\": 1,\n  \"host\" \"x\"\n}\n",
+		"c.toml":  "port = 1\nhost = \n",
+	})
+	path := func(name string) string { return filepath.Join(tmpDir, name) }
+
+	t.Setenv("LOADSRC_DATABASE_PASSWORD_FILE", path("missing"))
+
+	var conf loadErrorConfig
+	err := New("LOADSRC_", WithSources(
+		FilesSource(Required(path("c.yaml")), Required(path("c.json")), Required(path("c.toml")), Required(path("none.yaml"))),
+		EnvSource("LOADSRC_"),
+	)).Load(&conf)
+
+	var le *LoadError
+	if !errors.As(err, &le) {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+
+	expected := []Origin{
+		{Kind: OriginFile, Name: path("db.yaml"), Line: 1},
+		{Kind: OriginFile, Name: path("c.json"), Line: 3, Column: 10},
+		{Kind: OriginFile, Name: path("c.toml"), Line: 2, Column: 8},
+		{Kind: OriginFile, Name: path("none.yaml")},
+		{Kind: OriginEnv, Name: "LOADSRC_DATABASE_PASSWORD_FILE"},
+	}
+	if len(le.Problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %d:\n%v", len(expected), len(le.Problems), err)
+	}
+	for i, o := range expected {
+		p := le.Problems[i]
+		if p.Key != "" || p.Err == nil || p.Source.Kind != o.Kind || p.Source.Name != o.Name ||
+			p.Source.Line != o.Line || p.Source.Column != o.Column {
+			t.Errorf("expected problem %d from %v, got %v", i, o, p)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package mykonf
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Origin kinds.
+const (
+	OriginDefault  = "default"
+	OriginFile     = "file"
+	OriginEnv      = "env"
+	OriginDotenv   = "dotenv"
+	OriginFlag     = "flag"
+	OriginProvider = "provider"
+)
+
+// Origin is a source that set a key.
+type Origin struct {
+	Kind string
+	// Name is the file path, env var name or flag of the source.
+	Name string
+	// Line and Column locate the key in yaml files, 0 if unknown.
+	Line, Column int
+	// Value is the value the source set, before decoding.
+	Value any
+	// Secret marks values that were encrypted or read from a file by
+	// NAME_FILE or ${file:...}. Their Value is redacted like the values
+	// of secret fields.
+	Secret bool
+}
+
+func (o Origin) String() string {
+	switch o.Kind {
+	case OriginDefault:
+		return "default tag"
+	case OriginFile:
+		if o.Line > 0 && o.Column > 0 {
+			return fmt.Sprintf("file %s:%d:%d", o.Name, o.Line, o.Column)
+		}
+		if o.Line > 0 {
+			return fmt.Sprintf("file %s:%d", o.Name, o.Line)
+		}
+		return "file " + o.Name
+	}
+	return o.Kind + " " + o.Name
+}
+
+// Provenance holds, for each key path, the origins that set it in load
+// order. The last one is the value in effect.
+type Provenance map[string][]Origin
+
+// Explain returns the override chain of key, e.g.
+//
+//	port:
+//	  default tag = 8080
+//	  file config.yaml:3:1 = 9090
+//	  env APP_PORT = 9999 (in effect)
+func (p Provenance) Explain(key string) string {
+	chain := p[key]
+	if len(chain) == 0 {
+		return key + ": not set"
+	}
+
+	var b strings.Builder
+	b.WriteString(key + ":")
+	for i, o := range chain {
+		fmt.Fprintf(&b, "\n  %s = %v", o, o.Value)
+		if i == len(chain)-1 {
+			b.WriteString(" (in effect)")
+		}
+	}
+	return b.String()
+}
+
+// LoadProvenance is Load that also returns where each key came from.
+func (l *Loader) LoadProvenance(conf any) (Provenance, error) {
+	c, err := l.load(conf)
+	if err != nil {
+		return nil, err
+	}
+	return c.Provenance(), nil
+}
+
+// Provenance returns the origins of the keys loaded so far. The values of
+// secret fields and secret origins are redacted.
+func (c *LoadContext) Provenance() Provenance {
+	fk := c.fieldKeys()
+	p := make(Provenance)
+	for _, key := range c.Koanf.Keys() {
+		chain := slices.Clone(c.origins[key])
+		secret := fk.isSecret(key, c.Loader.delim)
+		for i := range chain {
+			if secret || chain[i].Secret {
+				chain[i].Value = redacted
+			}
+		}
+		p[key] = chain
+	}
+	return p
+}
+
+// protected wraps a value decrypted or read from a file while parsing a
+// file, until unprotect records its key as secret.
+type protected struct{ v any }
+
+// unprotect unwraps the protected values in v, the value at key, adding
+// their keys to secret if not nil, and returns it. Maps and slices are
+// unwrapped in place.
+func unprotect(v any, key, delim string, secret map[string]bool) any {
+	switch v := v.(type) {
+	case protected:
+		if secret != nil {
+			secret[key] = true
+		}
+		return v.v
+	case map[string]any:
+		for k, sub := range v {
+			sk := k
+			if key != "" {
+				sk = key + delim + k
+			}
+			v[k] = unprotect(sub, sk, delim, secret)
+		}
+	case []any:
+		// items are set by the key of the slice
+		for i, sub := range v {
+			v[i] = unprotect(sub, key, delim, secret)
+		}
+	}
+	return v
+}
@@ -0,0 +1,145 @@
+package mykonf
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoader_LoadProvenance(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "name: app\nport: 9090\ndatabase: !include db.yaml\n",
+		"db.yaml":     "host: localhost\n",
+	})
+	main := filepath.Join(tmpDir, "config.yaml")
+	db := filepath.Join(tmpDir, "db.yaml")
+
+	t.Setenv("PROV_PORT", "9999")
+
+	type Config struct {
+		Name     string `yaml:"name"`
+		Port     int    `yaml:"port" default:"8080"`
+		Timeout  string `yaml:"timeout" default:"30s"`
+		Database struct {
+			Host string `yaml:"host"`
+		} `yaml:"database"`
+	}
+
+	var conf Config
+	p, err := New("PROV_", WithSources(FileSource(main), EnvSource("PROV_"))).LoadProvenance(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	port := p["port"]
+	if len(port) != 3 {
+		t.Fatalf("expected 3 origins for port, got %v", port)
+	}
+	if port[0].Kind != OriginDefault || port[0].Value != 8080 {
+		t.Errorf("expected default tag 8080 first, got %+v", port[0])
+	}
+	if port[1].Kind != OriginFile || port[1].Name != main || port[1].Line != 2 || port[1].Column != 1 {
+		t.Errorf("expected %s:2:1 second, got %+v", main, port[1])
+	}
+	if port[2].Kind != OriginEnv || port[2].Name != "PROV_PORT" || port[2].Value != "9999" {
+		t.Errorf("expected env PROV_PORT last, got %+v", port[2])
+	}
+
+	host := p["database.host"]
+	if len(host) != 1 || host[0].Name != db || host[0].Line != 1 {
+		t.Errorf("expected database.host from %s:1, got %+v", db, host)
+	}
+
+	if timeout := p["timeout"]; len(timeout) != 1 || timeout[0].Kind != OriginDefault {
+		t.Errorf("expected timeout from default tag only, got %+v", timeout)
+	}
+
+	explain := p.Explain("port")
+	expected := "port:\n  default tag = 8080\n  file " + main + ":2:1 = 9090\n  env PROV_PORT = 9999 (in effect)"
+	if explain != expected {
+		t.Errorf("expected explain:\n%s\ngot:\n%s", expected, explain)
+	}
+
+	if explain := p.Explain("missing"); !strings.Contains(explain, "not set") {
+		t.Errorf("expected missing key to be not set, got %q", explain)
+	}
+}
+
+func TestOrigin_String(t *testing.T) {
+	tests := map[string]Origin{
+		"default tag":              {Kind: OriginDefault},
+		"file config.yaml:3:5":     {Kind: OriginFile, Name: "config.yaml", Line: 3, Column: 5},
+		"file config.json":         {Kind: OriginFile, Name: "config.json"},
+		"env APP_PORT":             {Kind: OriginEnv, Name: "APP_PORT"},
+		"flag --database.host":     {Kind: OriginFlag, Name: "--database.host"},
+		"dotenv APP_DATABASE_HOST": {Kind: OriginDotenv, Name: "APP_DATABASE_HOST"},
+	}
+
+	for expected, o := range tests {
+		if got := o.String(); got != expected {
+			t.Errorf("expected %q, got %q", expected, got)
+		}
+	}
+}
+
+func TestLoader_ProvenanceSecretOrigins(t *testing.T) {
+	tmpDir := t.TempDir()
+	id, keyFile := ageKeyFile(t, tmpDir)
+	enc, err := EncryptValue("enc-value", id.Recipient())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "token: " + enc + "\ndsn: db://${file:dsn.txt}\nport: ${file:port.txt}\n",
+		"dsn.txt":     "file-value\n",
+		"port.txt":    "not-a-port\n",
+		"name.txt":    "env-value\n",
+	})
+	t.Setenv("PROVSEC_AGE_KEY_FILE", keyFile)
+	t.Setenv("PROVSEC_NAME_FILE", filepath.Join(tmpDir, "name.txt"))
+
+	var conf struct {
+		Name  string `yaml:"name"`
+		Token string `yaml:"token"`
+		DSN   string `yaml:"dsn"`
+		Port  int    `yaml:"port"`
+	}
+	loader := New("PROVSEC_", WithSources(FileSource(filepath.Join(tmpDir, "config.yaml")), EnvSource("PROVSEC_")))
+
+	err = loader.Load(&conf)
+	var le *LoadError
+	if !errors.As(err, &le) {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	if len(le.Problems) != 1 || le.Problems[0].Value != redacted || !le.Problems[0].Source.Secret {
+		t.Errorf("expected a redacted problem for port, got %+v", le.Problems)
+	}
+	if strings.Contains(err.Error(), "not-a-port") {
+		t.Errorf("expected the value read from a file to be redacted, got:\n%v", err)
+	}
+
+	conf.Port = 0
+	writeFiles(t, tmpDir, map[string]string{"port.txt": "8080\n"})
+	p, err := loader.LoadProvenance(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if conf.Token != "enc-value" || conf.DSN != "db://file-value" || conf.Name != "env-value" {
+		t.Errorf("expected decrypted and file values, got %+v", conf)
+	}
+
+	for _, key := range []string{"token", "dsn", "name", "port"} {
+		o := p[key][len(p[key])-1]
+		if !o.Secret || o.Value != redacted {
+			t.Errorf("expected %s to have a redacted secret origin, got %+v", key, o)
+		}
+		for _, s := range []string{"enc-value", "file-value", "env-value", "8080"} {
+			if e := p.Explain(key); strings.Contains(e, s) {
+				t.Errorf("expected Explain to redact %s, got:\n%s", key, e)
+			}
+		}
+	}
+}
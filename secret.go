@@ -0,0 +1,71 @@
+package mykonf
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+)
+
+// Secret is a string that redacts itself when printed, logged or
+// marshaled. Only Reveal returns the value.
+type Secret string
+
+// Reveal returns the value.
+func (s Secret) Reveal() string { return string(s) }
+
+func (s Secret) String() string { return redact(len(s)) }
+
+func (s Secret) GoString() string { return `"` + redact(len(s)) + `"` }
+
+func (s Secret) MarshalJSON() ([]byte, error) { return json.Marshal(redact(len(s))) }
+
+func (s Secret) MarshalYAML() (any, error) { return redact(len(s)), nil }
+
+func (s Secret) LogValue() slog.Value { return slog.StringValue(redact(len(s))) }
+
+// SecretBytes is the []byte counterpart of Secret. It decodes from
+// strings as is, not split on commas.
+type SecretBytes []byte
+
+// Reveal returns the value.
+func (s SecretBytes) Reveal() []byte { return s }
+
+func (s SecretBytes) String() string { return redact(len(s)) }
+
+func (s SecretBytes) GoString() string { return `"` + redact(len(s)) + `"` }
+
+func (s SecretBytes) MarshalJSON() ([]byte, error) { return json.Marshal(redact(len(s))) }
+
+func (s SecretBytes) MarshalYAML() (any, error) { return redact(len(s)), nil }
+
+func (s SecretBytes) LogValue() slog.Value { return slog.StringValue(redact(len(s))) }
+
+// UnmarshalText sets s to a copy of text, so json strings decode as is
+// rather than as base64.
+func (s *SecretBytes) UnmarshalText(text []byte) error {
+	*s = append(SecretBytes(nil), text...)
+	return nil
+}
+
+// redact returns what a secret of n bytes prints as. Empty secrets print
+// empty, so that unset ones stand out.
+func redact(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return redacted
+}
+
+var (
+	secretType      = reflect.TypeFor[Secret]()
+	secretBytesType = reflect.TypeFor[SecretBytes]()
+)
+
+// isSecretType reports whether t, or what it points to, is Secret or
+// SecretBytes.
+func isSecretType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t == secretType || t == secretBytesType
+}
@@ -0,0 +1,122 @@
+package mykonf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+type secretConfig struct {
+	User     string      `yaml:"user" json:"user"`
+	Password Secret      `yaml:"password" json:"password"`
+	Key      SecretBytes `yaml:"key" json:"key"`
+}
+
+func TestSecret_Redacted(t *testing.T) {
+	conf := secretConfig{User: "admin", Password: "hunter2", Key: SecretBytes("k3y,with,commas")}
+
+	outputs := map[string]string{
+		"%v":  fmt.Sprintf("%v", conf),
+		"%+v": fmt.Sprintf("%+v", conf),
+		"%#v": fmt.Sprintf("%#v", conf),
+	}
+
+	b, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	outputs["json"] = string(b)
+
+	b, err = yaml.Marshal(conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	outputs["yaml"] = string(b)
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("loaded", "password", conf.Password, "key", conf.Key)
+	outputs["slog"] = buf.String()
+
+	for name, out := range outputs {
+		if strings.Contains(out, "hunter2") || strings.Contains(out, "k3y") {
+			t.Errorf("expected %s output to be redacted, got %s", name, out)
+		}
+		if !strings.Contains(out, redacted) {
+			t.Errorf("expected %s output to contain %s, got %s", name, redacted, out)
+		}
+	}
+
+	if conf.Password.Reveal() != "hunter2" {
+		t.Errorf("expected Reveal()=hunter2, got %q", conf.Password.Reveal())
+	}
+}
+
+func TestSecret_Empty(t *testing.T) {
+	if s := Secret("").String(); s != "" {
+		t.Errorf("expected empty secret to print empty, got %q", s)
+	}
+}
+
+func TestLoader_Secret(t *testing.T) {
+	t.Setenv("SECRET_PASSWORD", "hunter2")
+	t.Setenv("SECRET_KEY", "k3y,with,commas")
+
+	var conf secretConfig
+	p, err := New("SECRET_", WithSources(EnvSource("SECRET_"))).LoadProvenance(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Password.Reveal() != "hunter2" {
+		t.Errorf("expected Password=hunter2, got %q", conf.Password.Reveal())
+	}
+	if string(conf.Key.Reveal()) != "k3y,with,commas" {
+		t.Errorf("expected Key=k3y,with,commas, got %q", conf.Key.Reveal())
+	}
+	if s := p.Explain("password"); strings.Contains(s, "hunter2") {
+		t.Errorf("expected Explain to redact password, got:\n%s", s)
+	}
+}
+
+func TestLoader_SecretDefault(t *testing.T) {
+	var conf struct {
+		Gitea struct {
+			Url    string `yaml:"url"`
+			ApiKey Secret `yaml:"api_key" default:"api_key_here"`
+		} `yaml:"gitea"`
+	}
+	err := Load("SECDEF_", &conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Gitea.ApiKey.Reveal() != "api_key_here" {
+		t.Errorf("expected ApiKey=api_key_here, got %q", conf.Gitea.ApiKey.Reveal())
+	}
+	if s := fmt.Sprint(conf); strings.Contains(s, "api_key_here") {
+		t.Errorf("expected printed config to redact ApiKey, got %s", s)
+	}
+}
+
+func TestLoader_SecretLoadError(t *testing.T) {
+	var conf struct {
+		Token Secret `yaml:"token"`
+	}
+	err := New("SECERRS_", WithSources(
+		ProviderSource(mapProvider(map[string]any{"token": []any{"hunter2"}}), nil),
+	)).Load(&conf)
+
+	var le *LoadError
+	if !errors.As(err, &le) {
+		t.Fatalf("expected *LoadError, got %T: %v", err, err)
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("expected secret value to be redacted, got:\n%v", err)
+	}
+}
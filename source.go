@@ -0,0 +1,338 @@
+package mykonf
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/knadh/koanf/providers/env/v2"
+	"github.com/knadh/koanf/v2"
+)
+
+// Source is one configuration layer of a Loader.
+type Source interface {
+	Load(c *LoadContext) error
+}
+
+// SourceFunc adapts a function to a Source.
+type SourceFunc func(c *LoadContext) error
+
+func (f SourceFunc) Load(c *LoadContext) error { return f(c) }
+
+// ProviderSource loads a koanf provider as is.
+func ProviderSource(p koanf.Provider, pa koanf.Parser) Source {
+	return SourceFunc(func(c *LoadContext) error {
+		return c.Load(fmt.Sprintf("%T", p), p, pa)
+	})
+}
+
+// Layer is one file of a FilesSource.
+type Layer struct {
+	Path string
+	// Format overrides the format detected from the extension.
+	Format Format
+	// Optional layers are skipped when the file does not exist.
+	Optional bool
+}
+
+// Required returns a layer that must exist.
+func Required(path string) Layer { return Layer{Path: path} }
+
+// Optional returns a layer that is skipped when missing.
+func Optional(path string) Layer { return Layer{Path: path, Optional: true} }
+
+// Overlays returns the optional layers path, path.<profile> and
+// path.local, e.g. config.yaml, config.prod.yaml, config.local.yaml.
+// An empty profile is omitted.
+func Overlays(path, profile string) []Layer {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	layers := []Layer{Optional(path)}
+	if profile != "" {
+		layers = append(layers, Optional(base+"."+profile+ext))
+	}
+	return append(layers, Optional(base+".local"+ext))
+}
+
+// FileSource loads a config file. A missing file is skipped.
+func FileSource(path string) Source {
+	return FilesSource(Optional(path))
+}
+
+// FilesSource deep-merges config files in order, later layers overriding
+// earlier ones. Every layer is tried, so that all the files that cannot
+// be read are reported together.
+func FilesSource(layers ...Layer) Source {
+	return SourceFunc(func(c *LoadContext) error {
+		var errs []error
+		for _, layer := range layers {
+			errs = append(errs, c.loadFile(layer))
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// DirSource loads every *.yaml, *.yml, *.json, *.toml and *.hcl file in
+// dir, merged in lexical filename order. A missing dir is skipped.
+func DirSource(dir string) Source {
+	return SourceFunc(func(c *LoadContext) error {
+		c.AddFile(dir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		// ReadDir returns entries sorted by filename
+		var errs []error
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			switch filepath.Ext(e.Name()) {
+			case ".yaml", ".yml", ".json", ".toml", ".hcl":
+			default:
+				continue
+			}
+
+			errs = append(errs, c.loadFile(Required(filepath.Join(dir, e.Name()))))
+		}
+		return errors.Join(errs...)
+	})
+}
+
+func (c *LoadContext) loadFile(layer Layer) error {
+	c.AddFile(layer.Path)
+	_, err := os.Stat(layer.Path)
+	if err != nil && !os.IsExist(err) {
+		if layer.Optional {
+			return nil
+		}
+		return fileProblem(layer.Path, nil, err)
+	}
+
+	f := newFile(layer.Path, expandConf{
+		policy:   c.Loader.expand,
+		prefixes: c.Loader.expandPrefixes,
+		skip:     c.noExpand,
+	})
+	f.dec = c.decrypter()
+
+	format := layer.Format
+	if format == "" {
+		format = c.Loader.format
+	}
+	if format == "" {
+		format = FormatOf(layer.Path)
+	}
+	var m map[string]any
+	var pos map[string]position
+	parser := c.parser(format)
+	if parser != nil {
+		m, err = f.parse(parser, c.Loader.delim)
+	} else {
+		var files []string
+		m, pos, files, err = f.read(c.Loader.delim)
+		for _, p := range files {
+			c.AddFile(p)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	secret := make(map[string]bool)
+	unprotect(m, "", c.Loader.delim, secret)
+	k := koanf.New(c.Loader.delim)
+	err = k.Load(mapProvider(m), nil)
+	if err != nil {
+		return err
+	}
+	err = c.merge(k, func(key string) Origin {
+		o := Origin{Kind: OriginFile, Name: layer.Path, Secret: secret[key]}
+		if p, ok := pos[key]; ok {
+			o.Name, o.Line, o.Column = p.file, p.line, p.column
+		}
+		return o
+	})
+	if err != nil {
+		return err
+	}
+
+	// keys in the items of slices, e.g. ups[1].host, are not keys, but
+	// are located for strict mode
+	for key, p := range pos {
+		if strings.Contains(key, "[") && !c.Koanf.Exists(key) {
+			c.origins[key] = []Origin{{Kind: OriginFile, Name: p.file, Line: p.line, Column: p.column}}
+		}
+	}
+	return nil
+}
+
+// fileEnvSuffix marks env vars holding the path of a file to read the
+// value from, e.g. APP_DATABASE_PASSWORD_FILE=/run/secrets/db_password.
+const fileEnvSuffix = "_FILE"
+
+// EnvSource loads environment variables starting with prefix. Names are
+// mapped to keys with EnvToKey, unknown names are lowercased. NAME_FILE
+// sets the key of NAME to the contents of the file it points to, without
+// trailing newlines.
+func EnvSource(prefix string) Source {
+	return SourceFunc(func(c *LoadContext) error {
+		return c.loadEnv(OriginEnv, prefix, os.Environ)
+	})
+}
+
+// DotenvPaths returns .env and, if profile is not empty, .env.<profile>.
+func DotenvPaths(profile string) []string {
+	if profile == "" {
+		return []string{".env"}
+	}
+	return []string{".env", ".env." + profile}
+}
+
+// DotenvSource loads dotenv files as if their variables were set in the
+// environment, .env in the working directory when no paths are given.
+// Later files override earlier ones, missing files are skipped. Variables
+// set in the real environment always win, whatever the source order.
+func DotenvSource(prefix string, paths ...string) Source {
+	if len(paths) == 0 {
+		paths = DotenvPaths("")
+	}
+	return SourceFunc(func(c *LoadContext) error {
+		vars := make(map[string]string)
+		for _, path := range paths {
+			c.AddFile(path)
+			b, err := os.ReadFile(path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+
+			m, err := godotenv.UnmarshalBytes(b)
+			if err != nil {
+				return fileProblem(path, b, err)
+			}
+			maps.Copy(vars, m)
+		}
+
+		var environ []string
+		for k, v := range vars {
+			if _, ok := os.LookupEnv(k); ok {
+				continue
+			}
+			environ = append(environ, k+"="+v)
+		}
+		return c.loadEnv(OriginDotenv, prefix, func() []string { return environ })
+	})
+}
+
+func (c *LoadContext) loadEnv(kind, prefix string, environ func() []string) error {
+	names := make(map[string]string)
+	// fileNames holds the keys set by _FILE vars, with their names
+	fileNames := make(map[string]string)
+	var indexed []indexedVar
+	var errs []error
+	k := koanf.New(c.Loader.delim)
+	err := k.Load(env.Provider(c.Loader.delim, env.Opt{
+		Prefix: prefix,
+		TransformFunc: func(name, v string) (string, any) {
+			base, isFile := c.fileEnv(strings.TrimPrefix(name, prefix))
+			var value any = v
+			if isFile {
+				c.AddFile(v)
+				b, err := readSecretFile(v)
+				if err != nil {
+					errs = append(errs, Problem{Source: Origin{Kind: kind, Name: name}, Err: err})
+					return "", nil
+				}
+				value = b
+			}
+
+			if iv, ok := c.indexedEnv(base); ok {
+				iv.name, iv.value = name, value
+				indexed = append(indexed, iv)
+				c.envVars = append(c.envVars, envVar{kind: kind, prefix: prefix, name: name, key: iv.field.key})
+				return "", nil
+			}
+
+			key := c.envKey(base)
+			c.envVars = append(c.envVars, envVar{kind: kind, prefix: prefix, name: name, key: key})
+			if isFile {
+				fileNames[key] = name
+			} else {
+				names[key] = name
+			}
+			return key, value
+		},
+		EnvironFunc: environ,
+	}), nil)
+	if err != nil {
+		return err
+	}
+
+	for key, name := range fileNames {
+		if names[key] != "" {
+			errs = append(errs, Problem{
+				Source: Origin{Kind: kind, Name: name},
+				Err:    fmt.Errorf("%s is also set", names[key]),
+			})
+		}
+		names[key] = name
+	}
+	if len(indexed) > 0 {
+		errs = append(errs, c.setIndexed(k, kind, indexed, names))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	return c.merge(k, func(key string) Origin {
+		_, secret := fileNames[key]
+		return Origin{Kind: kind, Name: names[key], Secret: secret}
+	})
+}
+
+// fileEnv returns the env name without the _FILE suffix and true if name
+// is NAME_FILE for a known NAME. Names that bind to a field as is are
+// returned unchanged.
+func (c *LoadContext) fileEnv(name string) (string, bool) {
+	if c.knownEnv(name) {
+		return name, false
+	}
+	base, ok := strings.CutSuffix(name, fileEnvSuffix)
+	if !ok || !c.knownEnv(base) {
+		return name, false
+	}
+	return base, true
+}
+
+// knownEnv reports whether name, without prefix, binds to a field.
+func (c *LoadContext) knownEnv(name string) bool {
+	if _, ok := c.EnvToKey()[name]; ok {
+		return true
+	}
+	if _, ok := c.indexedEnv(name); ok {
+		return true
+	}
+	_, ok := c.mapEnv(name)
+	return ok
+}
+
+// readSecretFile returns the contents of path without trailing newlines.
+func readSecretFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,279 @@
+package mykonf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOverlays(t *testing.T) {
+	layers := Overlays("/etc/app/config.yaml", "prod")
+
+	expected := []Layer{
+		Optional("/etc/app/config.yaml"),
+		Optional("/etc/app/config.prod.yaml"),
+		Optional("/etc/app/config.local.yaml"),
+	}
+	if len(layers) != len(expected) {
+		t.Fatalf("expected %d layers, got %d: %v", len(expected), len(layers), layers)
+	}
+	for i, l := range expected {
+		if layers[i] != l {
+			t.Errorf("expected layers[%d]=%v, got %v", i, l, layers[i])
+		}
+	}
+}
+
+func TestOverlays_NoProfile(t *testing.T) {
+	layers := Overlays("config.yaml", "")
+
+	if len(layers) != 2 || layers[1].Path != "config.local.yaml" {
+		t.Errorf("expected config.yaml and config.local.yaml, got %v", layers)
+	}
+}
+
+func TestFilesSource_DeepMerge(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := filepath.Join(tmpDir, "config.yaml")
+	local := filepath.Join(tmpDir, "config.local.yaml")
+
+	content := []byte("database:\n  host: localhost\n  port: 5432\n")
+	if err := os.WriteFile(base, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	content = []byte("database:\n  port: 6543\n")
+	if err := os.WriteFile(local, content, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	type Config struct {
+		Database struct {
+			Host string `yaml:"host"`
+			Port int    `yaml:"port"`
+		} `yaml:"database"`
+	}
+
+	var conf Config
+	err := New("TEST_", WithSources(FilesSource(Overlays(base, "prod")...))).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Database.Host != "localhost" {
+		t.Errorf("expected Database.Host='localhost', got %q", conf.Database.Host)
+	}
+
+	if conf.Database.Port != 6543 {
+		t.Errorf("expected Database.Port=6543, got %d", conf.Database.Port)
+	}
+}
+
+func TestFilesSource_RequiredMissing(t *testing.T) {
+	type Config struct {
+		Name string `yaml:"name"`
+	}
+
+	var conf Config
+	err := New("TEST_", WithSources(FilesSource(Required("/nonexistent/config.yaml")))).Load(&conf)
+
+	if err == nil {
+		t.Fatal("expected error for missing required layer")
+	}
+}
+
+func TestDirSource_LexicalOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	main := filepath.Join(tmpDir, "config.yaml")
+	confd := filepath.Join(tmpDir, "conf.d")
+
+	if err := os.Mkdir(confd, 0755); err != nil {
+		t.Fatalf("failed to create conf.d: %v", err)
+	}
+
+	t.Setenv("FRAG_HOST", "fraghost")
+
+	files := map[string]string{
+		main:                              "name: main\nport: 8080\nhost: mainhost\n",
+		filepath.Join(confd, "20-b.yml"):  "port: 9092\n",
+		filepath.Join(confd, "10-a.yaml"): "port: 9091\nhost: $FRAG_HOST\n",
+		filepath.Join(confd, "30-c.json"): `{"name": "json"}`,
+		filepath.Join(confd, "40-d.txt"):  "name: ignored\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	type Config struct {
+		Name string `yaml:"name"`
+		Host string `yaml:"host"`
+		Port int    `yaml:"port"`
+	}
+
+	var conf Config
+	err := New("TEST_", WithSources(FileSource(main), DirSource(confd))).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "json" {
+		t.Errorf("expected Name='json', got %q", conf.Name)
+	}
+
+	if conf.Host != "fraghost" {
+		t.Errorf("expected Host='fraghost', got %q", conf.Host)
+	}
+
+	if conf.Port != 9092 {
+		t.Errorf("expected Port=9092, got %d", conf.Port)
+	}
+}
+
+func TestDirSource_Missing(t *testing.T) {
+	type Config struct {
+		Name string `yaml:"name" default:"default"`
+	}
+
+	var conf Config
+	err := New("TEST_", WithSources(DirSource("/nonexistent/conf.d"))).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "default" {
+		t.Errorf("expected Name='default', got %q", conf.Name)
+	}
+}
+
+func TestDotenvPaths(t *testing.T) {
+	if paths := DotenvPaths(""); len(paths) != 1 || paths[0] != ".env" {
+		t.Errorf("expected [.env], got %v", paths)
+	}
+
+	if paths := DotenvPaths("dev"); len(paths) != 2 || paths[1] != ".env.dev" {
+		t.Errorf("expected [.env .env.dev], got %v", paths)
+	}
+}
+
+func TestDotenvSource(t *testing.T) {
+	tmpDir := t.TempDir()
+	dotenv := filepath.Join(tmpDir, ".env")
+	profile := filepath.Join(tmpDir, ".env.dev")
+
+	writeFiles(t, tmpDir, map[string]string{
+		".env": "# database settings\n" +
+			"export DOT_DATABASE_HOST=filehost\n" +
+			"DOT_DATABASE_PORT=5432 # inline comment\n" +
+			"DOT_NAME='single quoted'\n" +
+			"DOT_CERT=\"line1\\nline2\"\n" +
+			"OTHER_NAME=ignored\n",
+		".env.dev": "DOT_DATABASE_PORT=6543\n",
+	})
+
+	t.Setenv("DOT_DATABASE_HOST", "realhost")
+
+	type Config struct {
+		Name     string `yaml:"name"`
+		Cert     string `yaml:"cert"`
+		Database struct {
+			Host string `yaml:"host"`
+			Port int    `yaml:"port"`
+		} `yaml:"database"`
+	}
+
+	var conf Config
+	err := New("DOT_", WithSources(
+		EnvSource("DOT_"),
+		DotenvSource("DOT_", dotenv, profile, filepath.Join(tmpDir, ".env.missing")),
+	)).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "single quoted" {
+		t.Errorf("expected Name='single quoted', got %q", conf.Name)
+	}
+
+	if conf.Cert != "line1\nline2" {
+		t.Errorf("expected Cert='line1\\nline2', got %q", conf.Cert)
+	}
+
+	if conf.Database.Host != "realhost" {
+		t.Errorf("expected Database.Host='realhost' from real env, got %q", conf.Database.Host)
+	}
+
+	if conf.Database.Port != 6543 {
+		t.Errorf("expected Database.Port=6543 from profile, got %d", conf.Database.Port)
+	}
+}
+
+func TestEnvSource_FileSuffix(t *testing.T) {
+	tmpDir := t.TempDir()
+	secret := filepath.Join(tmpDir, "db_password")
+	writeFiles(t, tmpDir, map[string]string{
+		"db_password": "s3cret\n\n",
+	})
+
+	t.Setenv("SECFILE_DATABASE_PASSWORD_FILE", secret)
+	t.Setenv("SECFILE_LOG_FILE", "/var/log/app.log")
+
+	type Config struct {
+		LogFile  string `yaml:"log_file"`
+		Database struct {
+			Password string `yaml:"password"`
+		} `yaml:"database"`
+	}
+
+	var conf Config
+	p, err := New("SECFILE_", WithSources(EnvSource("SECFILE_"))).LoadProvenance(&conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Database.Password != "s3cret" {
+		t.Errorf("expected Database.Password=s3cret, got %q", conf.Database.Password)
+	}
+	if conf.LogFile != "/var/log/app.log" {
+		t.Errorf("expected LogFile=/var/log/app.log, got %q", conf.LogFile)
+	}
+	if o := p["database.password"][0]; o.Name != "SECFILE_DATABASE_PASSWORD_FILE" {
+		t.Errorf("expected database.password from SECFILE_DATABASE_PASSWORD_FILE, got %v", o)
+	}
+}
+
+func TestEnvSource_FileSuffixErrors(t *testing.T) {
+	type Config struct {
+		Password string `yaml:"password"`
+		Token    string `yaml:"token"`
+	}
+
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{"token": "abc"})
+
+	t.Setenv("SECERR_PASSWORD_FILE", filepath.Join(tmpDir, "missing"))
+	t.Setenv("SECERR_TOKEN", "abc")
+	t.Setenv("SECERR_TOKEN_FILE", filepath.Join(tmpDir, "token"))
+
+	var conf Config
+	err := New("SECERR_", WithSources(EnvSource("SECERR_"))).Load(&conf)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	msg := err.Error()
+	expected := []string{
+		"env SECERR_PASSWORD_FILE: open " + filepath.Join(tmpDir, "missing"),
+		"env SECERR_TOKEN_FILE: SECERR_TOKEN is also set",
+	}
+	for _, e := range expected {
+		if !strings.Contains(msg, e) {
+			t.Errorf("expected error to contain %q, got:\n%s", e, msg)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package mykonf
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds the current config of type T. It is safe for concurrent
+// use, readers never block on reloads. The zero value is an empty Store.
+type Store[T any] struct {
+	p atomic.Pointer[T]
+
+	mu     sync.Mutex
+	subs   []subscription[T]
+	nextID int
+}
+
+type subscription[T any] struct {
+	id int
+	fn func(old, new *T)
+}
+
+// Get returns the current config, nil before the first Swap.
+func (s *Store[T]) Get() *T { return s.p.Load() }
+
+// Swap stores conf, notifies subscribers and returns the previous config.
+func (s *Store[T]) Swap(conf *T) (old *T) {
+	old = s.p.Swap(conf)
+
+	s.mu.Lock()
+	subs := s.subs
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.fn(old, conf)
+	}
+	return old
+}
+
+// Subscribe calls fn after every Swap until unsubscribe is called.
+func (s *Store[T]) Subscribe(fn func(old, new *T)) (unsubscribe func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	// copy on write, Swap iterates without the lock
+	s.subs = append(s.subs[:len(s.subs):len(s.subs)], subscription[T]{id: id, fn: fn})
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		subs := make([]subscription[T], 0, len(s.subs))
+		for _, sub := range s.subs {
+			if sub.id != id {
+				subs = append(subs, sub)
+			}
+		}
+		s.subs = subs
+	}
+}
+
+// Load loads a new T with l and swaps it in. The current config is kept
+// when loading fails.
+func (s *Store[T]) Load(l *Loader) error {
+	conf := new(T)
+	err := l.Load(conf)
+	if err != nil {
+		return err
+	}
+	s.Swap(conf)
+	return nil
+}
+
+// Watch is Watch swapping every loaded config into s.
+func (s *Store[T]) Watch(ctx context.Context, l *Loader, opts ...WatchOption) error {
+	return Watch(ctx, l, func(_, conf *T) {
+		s.Swap(conf)
+	}, opts...)
+}
@@ -0,0 +1,172 @@
+package mykonf
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadAs(t *testing.T) {
+	t.Setenv("AS_NAME", "typed")
+
+	type Config struct {
+		Name string `yaml:"name"`
+		Port int    `yaml:"port" default:"8080"`
+	}
+
+	conf, err := LoadAs[Config]("AS_")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Name != "typed" {
+		t.Errorf("expected Name='typed', got %q", conf.Name)
+	}
+
+	if conf.Port != 8080 {
+		t.Errorf("expected Port=8080, got %d", conf.Port)
+	}
+}
+
+func TestStore_SwapSubscribe(t *testing.T) {
+	type Config struct {
+		Name string
+	}
+
+	var s Store[Config]
+	if s.Get() != nil {
+		t.Fatal("expected nil config in empty store")
+	}
+
+	var got [][2]*Config
+	unsubscribe := s.Subscribe(func(old, new *Config) {
+		got = append(got, [2]*Config{old, new})
+	})
+
+	first := &Config{Name: "first"}
+	second := &Config{Name: "second"}
+
+	if old := s.Swap(first); old != nil {
+		t.Errorf("expected nil old config, got %+v", old)
+	}
+	if old := s.Swap(second); old != first {
+		t.Errorf("expected old config %+v, got %+v", first, old)
+	}
+
+	unsubscribe()
+	s.Swap(first)
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(got))
+	}
+	if got[0][0] != nil || got[0][1] != first || got[1][0] != first || got[1][1] != second {
+		t.Errorf("unexpected notifications: %v", got)
+	}
+
+	if s.Get() != first {
+		t.Errorf("expected current config %+v, got %+v", first, s.Get())
+	}
+}
+
+func TestStore_ConcurrentReaders(t *testing.T) {
+	type Config struct {
+		N int
+	}
+
+	var s Store[Config]
+	s.Swap(&Config{})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 1000 {
+				if s.Get() == nil {
+					t.Error("expected non-nil config")
+					return
+				}
+			}
+		})
+	}
+	for i := range 1000 {
+		s.Swap(&Config{N: i})
+	}
+	wg.Wait()
+}
+
+func TestStore_Watch(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{"config.yaml": "name: first\n"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var s Store[watchConfig]
+	changed := make(chan string, 4)
+	s.Subscribe(func(_, new *watchConfig) { changed <- new.Name })
+
+	loader := New("TEST_", WithSources(FileSource(filepath.Join(tmpDir, "config.yaml"))))
+	err := s.Watch(ctx, loader, WithDebounce(50*time.Millisecond))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if name := <-changed; name != "first" || s.Get().Name != "first" {
+		t.Fatalf("expected Name='first', got %q", name)
+	}
+
+	writeFiles(t, tmpDir, map[string]string{"config.yaml": "name: second\n"})
+
+	select {
+	case name := <-changed:
+		if name != "second" || s.Get().Name != "second" {
+			t.Errorf("expected Name='second', got %q", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+}
+
+func TestStore_LoadOnce(t *testing.T) {
+	t.Setenv("ONCE_NAME", "lazy")
+
+	type Config struct {
+		Name string `yaml:"name"`
+		Port int    `yaml:"port" default:"8080"`
+	}
+
+	var s Store[Config]
+	var swaps atomic.Int32
+	s.Subscribe(func(_, _ *Config) { swaps.Add(1) })
+
+	load := sync.OnceValue(func() error {
+		return s.Load(New("ONCE_"))
+	})
+	get := func() *Config {
+		if err := load(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return s.Get()
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conf := get()
+			if conf == nil || conf.Name != "lazy" || conf.Port != 8080 {
+				t.Errorf("expected Name=lazy Port=8080, got %+v", conf)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := swaps.Load(); n != 1 {
+		t.Errorf("expected concurrent first calls to load once, got %d loads", n)
+	}
+}
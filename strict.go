@@ -0,0 +1,289 @@
+package mykonf
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	kmaps "github.com/knadh/koanf/maps"
+)
+
+// fieldKeys holds the key paths of a config struct, lowercased since
+// decoding matches field names case-insensitively.
+type fieldKeys struct {
+	keys []string
+	// open holds the leaves that accept any sub key, such as maps.
+	open  map[string]bool
+	known map[string]bool
+	// types holds the Go type of each key.
+	types map[string]reflect.Type
+	// secret holds the keys whose values must not be shown, the fields
+	// of type Secret or tagged secret:"true". Their sub keys are secret
+	// too.
+	secret map[string]bool
+	// raw holds the keys tagged expand:"false", whose values and sub
+	// values are not expanded.
+	raw map[string]bool
+	// elems holds the item types of slices and maps of structs, whose
+	// keys are checked against the fields of the item. Their fieldKeys
+	// are built on demand, so that recursive types end.
+	elems    map[string]reflect.Type
+	elemKeys map[string]*fieldKeys
+	tag      string
+}
+
+func newFieldKeys(conf any, tag, delim string) *fieldKeys {
+	fk := &fieldKeys{
+		open:   make(map[string]bool),
+		known:  make(map[string]bool),
+		types:  make(map[string]reflect.Type),
+		secret: make(map[string]bool),
+		raw:    make(map[string]bool),
+		elems:  make(map[string]reflect.Type),
+		tag:    tag,
+	}
+	walkFields(reflect.TypeOf(conf), "", tag, delim, func(key string, field reflect.StructField, leaf bool) {
+		fk.keys = append(fk.keys, key)
+		key = strings.ToLower(key)
+		fk.known[key] = true
+		fk.types[key] = field.Type
+
+		if field.Tag.Get("secret") == "true" || isSecretType(field.Type) {
+			fk.secret[key] = true
+		}
+		if field.Tag.Get("expand") == "false" {
+			fk.raw[key] = true
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if leaf && (ft.Kind() == reflect.Map || ft.Kind() == reflect.Interface) {
+			fk.open[key] = true
+		}
+		if !leaf {
+			return
+		}
+		if elem, ok := structElem(ft); ok {
+			fk.elems[key] = elem
+		} else if elem, ok := mapElem(ft); ok {
+			fk.elems[key] = elem
+		}
+	})
+	return fk
+}
+
+// has reports whether key binds to a field.
+func (fk *fieldKeys) has(key, delim string) bool {
+	key = strings.ToLower(key)
+	if fk.known[key] {
+		return true
+	}
+
+	for i := range len(key) {
+		if strings.HasPrefix(key[i:], delim) && fk.open[key[:i]] {
+			return fk.elemHas(key[:i], key[i+len(delim):], delim)
+		}
+	}
+	return false
+}
+
+// elemHas reports whether rest, a sub key of the open key parent, binds
+// to a field. Sub keys of a map of structs are checked against the
+// fields of its values, any other ones bind.
+func (fk *fieldKeys) elemHas(parent, rest, delim string) bool {
+	ek := fk.elem(parent, delim)
+	if ek == nil {
+		return true
+	}
+	_, sub, ok := strings.Cut(rest, delim)
+	return !ok || ek.has(sub, delim)
+}
+
+// elem returns the fieldKeys of the items of key, nil unless key is a
+// slice or map of structs.
+func (fk *fieldKeys) elem(key, delim string) *fieldKeys {
+	key = strings.ToLower(key)
+	t, ok := fk.elems[key]
+	if !ok {
+		return nil
+	}
+	if fk.elemKeys == nil {
+		fk.elemKeys = make(map[string]*fieldKeys)
+	}
+	if fk.elemKeys[key] == nil {
+		fk.elemKeys[key] = newFieldKeys(reflect.Zero(reflect.PointerTo(t)).Interface(), fk.tag, delim)
+	}
+	return fk.elemKeys[key]
+}
+
+// suggest returns the closest valid key to key, which binds to no
+// field, or "". Keys in the values of maps of structs keep their map key.
+func (fk *fieldKeys) suggest(key, delim string) string {
+	lower := strings.ToLower(key)
+	for i := range len(key) {
+		if !strings.HasPrefix(key[i:], delim) || !fk.open[lower[:i]] {
+			continue
+		}
+		ek := fk.elem(key[:i], delim)
+		mapKey, sub, ok := strings.Cut(key[i+len(delim):], delim)
+		if ek == nil || !ok {
+			break
+		}
+		if s := ek.suggest(sub, delim); s != "" {
+			return key[:i] + delim + mapKey + delim + s
+		}
+		return ""
+	}
+	return closest(key, fk.keys)
+}
+
+// unknownItems calls report for every key of the items of v, the value
+// of the slice of structs at key, that binds to no field, along with the
+// closest valid key or "". Keys are shown as key[i].sub.
+func (fk *fieldKeys) unknownItems(key string, v any, delim string, report func(key, closest string)) {
+	items, ok := v.([]any)
+	ek := fk.elem(key, delim)
+	if !ok || ek == nil {
+		return
+	}
+
+	for i, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		prefix := fmt.Sprintf("%s[%d]%s", key, i, delim)
+		flat, _ := kmaps.Flatten(m, nil, delim)
+		for _, sub := range slices.Sorted(maps.Keys(flat)) {
+			if !ek.has(sub, delim) {
+				s := ek.suggest(sub, delim)
+				if s != "" {
+					s = prefix + s
+				}
+				report(prefix+sub, s)
+				continue
+			}
+			ek.unknownItems(sub, flat[sub], delim, func(k, s string) {
+				if s != "" {
+					s = prefix + s
+				}
+				report(prefix+k, s)
+			})
+		}
+	}
+}
+
+// isSecret reports whether the value of key must be redacted.
+func (fk *fieldKeys) isSecret(key, delim string) bool {
+	return under(fk.secret, key, delim)
+}
+
+// isRaw reports whether the value of key must not be expanded.
+func (fk *fieldKeys) isRaw(key, delim string) bool {
+	return under(fk.raw, key, delim)
+}
+
+// under reports whether key, or one of its parents, is in m.
+func under(m map[string]bool, key, delim string) bool {
+	key = strings.ToLower(key)
+	for {
+		if m[key] {
+			return true
+		}
+		i := strings.LastIndex(key, delim)
+		if i < 0 {
+			return false
+		}
+		key = key[:i]
+	}
+}
+
+// noExpand reports whether the value of key must not be expanded.
+func (c *LoadContext) noExpand(key string) bool {
+	return c.fieldKeys().isRaw(key, c.Loader.delim)
+}
+
+func (c *LoadContext) fieldKeys() *fieldKeys {
+	if c.fields == nil {
+		c.fields = newFieldKeys(c.Conf, c.Loader.tag, c.Loader.delim)
+	}
+	return c.fields
+}
+
+// checkUnknown reports every key, except env ones, that binds to no
+// field of Conf, along with the source that set it and the closest valid
+// key.
+func (c *LoadContext) checkUnknown() error {
+	delim := c.Loader.delim
+	fk := c.fieldKeys()
+
+	keys := c.Koanf.Keys()
+	slices.Sort(keys)
+
+	var errs []error
+	report := func(o Origin, key, suggestion string) {
+		msg := fmt.Sprintf("unknown key %q", key)
+		switch {
+		case o.Line > 0:
+			msg = fmt.Sprintf("%s:%d:%d: %s", o.Name, o.Line, o.Column, msg)
+		case o.Name != "":
+			msg = o.Name + ": " + msg
+		}
+		if suggestion != "" {
+			msg += fmt.Sprintf(", did you mean %q?", suggestion)
+		}
+		errs = append(errs, errors.New(msg))
+	}
+
+	for _, key := range keys {
+		o := c.origin(key)
+		switch o.Kind {
+		case OriginEnv, OriginDotenv:
+			continue
+		}
+
+		if !fk.has(key, delim) {
+			report(o, key, fk.suggest(key, delim))
+			continue
+		}
+		// items of slices of structs are not keys
+		fk.unknownItems(key, c.Koanf.Get(key), delim, func(k, s string) {
+			if io := c.origin(k); io.Kind != "" {
+				report(io, k, s)
+				return
+			}
+			report(o, k, s)
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// checkUnusedEnv reports prefixed env vars, including dotenv ones, that
+// bind to no field of Conf, along with the closest valid env name.
+func (c *LoadContext) checkUnusedEnv() error {
+	fk := c.fieldKeys()
+
+	var errs []error
+	for _, v := range c.envVars {
+		if fk.has(v.key, c.Loader.delim) || slices.Contains(controlEnvs, strings.TrimPrefix(v.name, v.prefix)) {
+			continue
+		}
+
+		var names []string
+		for name := range c.EnvToKey() {
+			names = append(names, v.prefix+name)
+		}
+
+		msg := fmt.Sprintf("%s %s binds to no field", v.kind, v.name)
+		if s := closest(v.name, names); s != "" {
+			msg += fmt.Sprintf(", did you mean %s?", s)
+		}
+		errs = append(errs, errors.New(msg))
+	}
+	return errors.Join(errs...)
+}
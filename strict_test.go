@@ -0,0 +1,199 @@
+package mykonf
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type strictConfig struct {
+	Name     string            `yaml:"name"`
+	Labels   map[string]string `yaml:"labels"`
+	Database struct {
+		Host    string `yaml:"host"`
+		Timeout string `yaml:"timeout"`
+	} `yaml:"database"`
+}
+
+func TestLoader_StrictUnknownKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := filepath.Join(tmpDir, "config.yaml")
+	local := filepath.Join(tmpDir, "config.local.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml":       "name: app\nlabels:\n  team: core\ndatabse:\n  host: localhost\n",
+		"config.local.yaml": "database:\n  timout: 5s\n",
+	})
+
+	t.Setenv("STRICT_UNKNOWN", "ignored by strict")
+
+	var conf strictConfig
+	err := New("STRICT_",
+		WithSources(FilesSource(Optional(base), Optional(local)), EnvSource("STRICT_")),
+		WithStrict(true),
+	).Load(&conf)
+
+	if err == nil {
+		t.Fatal("expected error for unknown keys")
+	}
+
+	msg := err.Error()
+	expected := []string{
+		base + `:5:3: unknown key "databse.host", did you mean "database.host"?`,
+		local + `:2:3: unknown key "database.timout", did you mean "database.timeout"?`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(msg, e) {
+			t.Errorf("expected error to contain %q, got:\n%s", e, msg)
+		}
+	}
+
+	if strings.Contains(msg, `"unknown"`) {
+		t.Errorf("env keys should not be reported by strict mode, got:\n%s", msg)
+	}
+}
+
+func TestLoader_StrictUnknownItemKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yaml")
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "ups:\n  - host: a\n  - hoost: b\n    typo: c\nnamed:\n  primary:\n    hots: d\n",
+	})
+
+	type Upstream struct {
+		Host string `yaml:"host"`
+	}
+	var conf struct {
+		Ups   []Upstream          `yaml:"ups"`
+		Named map[string]Upstream `yaml:"named"`
+	}
+	err := New("STRICT_", WithSources(FileSource(path)), WithStrict(true)).Load(&conf)
+
+	if err == nil {
+		t.Fatal("expected error for unknown keys")
+	}
+
+	msg := err.Error()
+	expected := []string{
+		path + `:3:5: unknown key "ups[1].hoost", did you mean "ups[1].host"?`,
+		path + `:4:5: unknown key "ups[1].typo"`,
+		path + `:7:5: unknown key "named.primary.hots", did you mean "named.primary.host"?`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(msg, e) {
+			t.Errorf("expected error to contain %q, got:\n%s", e, msg)
+		}
+	}
+	if strings.Contains(msg, `"ups[0].host"`) {
+		t.Errorf("valid item keys should not be reported, got:\n%s", msg)
+	}
+}
+
+func TestLoader_StrictValid(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "Name: app\nlabels:\n  any: value\ndatabase:\n  host: localhost\n",
+	})
+
+	var conf strictConfig
+	err := New("TEST_",
+		WithSources(FileSource(filepath.Join(tmpDir, "config.yaml"))),
+		WithStrict(true),
+	).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if conf.Labels["any"] != "value" {
+		t.Errorf("expected Labels[any]='value', got %q", conf.Labels["any"])
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"database", "database.host", "name"}
+
+	if s := closest("databse", candidates); s != "database" {
+		t.Errorf("expected 'database', got %q", s)
+	}
+
+	if s := closest("completely_different", candidates); s != "" {
+		t.Errorf("expected no suggestion, got %q", s)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"databse", "database", 1},
+	}
+
+	for _, tt := range tests {
+		if d := levenshtein(tt.a, tt.b); d != tt.d {
+			t.Errorf("levenshtein(%q, %q): expected %d, got %d", tt.a, tt.b, tt.d, d)
+		}
+	}
+}
+
+func TestLoader_UnusedEnvError(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{".env": "UNUSED_NAEM=typo\n"})
+
+	t.Setenv("UNUSED_SERVER_CONFIG", "/nonexistent/config.yaml")
+	t.Setenv("UNUSED_NAME", "app")
+	t.Setenv("UNUSED_DATABSE_HOST", "localhost")
+
+	var conf strictConfig
+	err := New("UNUSED_",
+		WithSources(EnvSource("UNUSED_"), DotenvSource("UNUSED_", filepath.Join(tmpDir, ".env"))),
+		WithUnusedEnv(UnusedEnvError),
+	).Load(&conf)
+
+	if err == nil {
+		t.Fatal("expected error for unused env vars")
+	}
+
+	msg := err.Error()
+	expected := []string{
+		"env UNUSED_DATABSE_HOST binds to no field, did you mean UNUSED_DATABASE_HOST?",
+		"dotenv UNUSED_NAEM binds to no field, did you mean UNUSED_NAME?",
+	}
+	for _, e := range expected {
+		if !strings.Contains(msg, e) {
+			t.Errorf("expected error to contain %q, got:\n%s", e, msg)
+		}
+	}
+
+	if strings.Contains(msg, "UNUSED_SERVER_CONFIG") || strings.Contains(msg, "UNUSED_NAME ") {
+		t.Errorf("bound and control env vars should not be reported, got:\n%s", msg)
+	}
+}
+
+func TestLoader_UnusedEnvWarn(t *testing.T) {
+	t.Setenv("WARN_NAEM", "typo")
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	var conf strictConfig
+	err := New("WARN_",
+		WithSources(EnvSource("WARN_")),
+		WithUnusedEnv(UnusedEnvWarn),
+	).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "env WARN_NAEM binds to no field, did you mean WARN_NAME?") {
+		t.Errorf("expected warning about WARN_NAEM, got %q", buf.String())
+	}
+}
@@ -0,0 +1,213 @@
+package mykonf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Validator is implemented by config structs, at any depth, that check
+// themselves after loading.
+type Validator interface {
+	Validate() error
+}
+
+// FieldError is a validation failure of one key.
+type FieldError struct {
+	// Key is the key path, empty for the root.
+	Key string
+	// Env is the env var that sets Key, empty if there is none.
+	Env string
+	Err error
+}
+
+func (e FieldError) Error() string {
+	switch {
+	case e.Key == "":
+		return e.Err.Error()
+	case e.Env == "":
+		return fmt.Sprintf("%s: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s (%s): %v", e.Key, e.Env, e.Err)
+}
+
+func (e FieldError) Unwrap() error { return e.Err }
+
+// ValidationError lists every key that failed validation.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid config:")
+	for _, f := range e.Fields {
+		b.WriteString("\n  " + f.Error())
+	}
+	return b.String()
+}
+
+// validate checks the validate tags of Conf, then calls Validate on every
+// struct implementing Validator, and reports all failures at once.
+func (c *LoadContext) validate() error {
+	var fields []FieldError
+
+	err := c.validator().Struct(c.Conf)
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		for _, fe := range verrs {
+			// strip the root type name and inline structs
+			parts := strings.Split(fe.Namespace(), ".")[1:]
+			parts = slices.DeleteFunc(parts, func(p string) bool { return p == inlineName })
+			key := strings.Join(parts, c.Loader.delim)
+			fields = append(fields, c.fieldError(key, errors.New(validationMessage(fe))))
+		}
+	} else if err != nil {
+		var invalid *validator.InvalidValidationError
+		if !errors.As(err, &invalid) {
+			return err
+		}
+		// not a struct, nothing to check
+	}
+
+	c.callValidators(reflect.ValueOf(c.Conf), "", &fields)
+
+	// inline structs share the key and the promoted Validate of their
+	// parent
+	fields = slices.CompactFunc(fields, func(a, b FieldError) bool {
+		return a.Key == b.Key && a.Err.Error() == b.Err.Error()
+	})
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// inlineName names inline structs in validation namespaces, so that they
+// can be removed from key paths.
+const inlineName = "\x00inline"
+
+func (c *LoadContext) validator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	tag := c.Loader.tag
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, ok := fieldName(field, tag)
+		if !ok {
+			return "-"
+		}
+		if isInline(field, tag) {
+			return inlineName
+		}
+		return name
+	})
+	return v
+}
+
+func (c *LoadContext) fieldError(key string, err error) FieldError {
+	fe := FieldError{Key: key, Err: err}
+	if _, ok := c.EnvToKey()[envName(key, c.Loader.delim)]; ok && key != "" {
+		fe.Env = c.Loader.envPrefix + envName(key, c.Loader.delim)
+	}
+	return fe
+}
+
+// callValidators calls Validate on v and everything below it, parents
+// first.
+func (c *LoadContext) callValidators(v reflect.Value, key string, fields *[]FieldError) {
+	for v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return
+	}
+
+	if v.CanInterface() {
+		if val, ok := v.Interface().(Validator); ok {
+			if err := val.Validate(); err != nil {
+				*fields = append(*fields, c.fieldError(key, err))
+			}
+		} else if v.CanAddr() {
+			if val, ok := v.Addr().Interface().(Validator); ok {
+				if err := val.Validate(); err != nil {
+					*fields = append(*fields, c.fieldError(key, err))
+				}
+			}
+		}
+	}
+
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	join := func(name string) string {
+		if key == "" {
+			return name
+		}
+		return key + c.Loader.delim + name
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if isLeafStruct(v.Type()) {
+			return
+		}
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			name, ok := fieldName(field, c.Loader.tag)
+			if !ok {
+				continue
+			}
+			if isInline(field, c.Loader.tag) {
+				c.callValidators(v.Field(i), key, fields)
+				continue
+			}
+			c.callValidators(v.Field(i), join(name), fields)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			c.callValidators(v.Index(i), fmt.Sprintf("%s[%d]", key, i), fields)
+		}
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// map values are not addressable, copy them for pointer
+			// receivers
+			e := reflect.New(iter.Value().Type()).Elem()
+			e.Set(iter.Value())
+			c.callValidators(e, join(fmt.Sprint(iter.Key().Interface())), fields)
+		}
+	}
+}
+
+// validationMessage describes a failed validate tag.
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "url":
+		return "must be a URL"
+	case "hostname":
+		return "must be a hostname"
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed %s=%s", fe.Tag(), fe.Param())
+	}
+	return "failed " + fe.Tag()
+}
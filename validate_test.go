@@ -0,0 +1,108 @@
+package mykonf
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type validateDatabase struct {
+	Host string `yaml:"host" validate:"required,hostname"`
+	Port int    `yaml:"port" validate:"min=1,max=65535"`
+}
+
+func (d validateDatabase) Validate() error {
+	if d.Host == "forbidden" {
+		return errors.New("host is forbidden")
+	}
+	return nil
+}
+
+type validateUpstream struct {
+	Url string `yaml:"url"`
+}
+
+func (u *validateUpstream) Validate() error {
+	if !strings.HasPrefix(u.Url, "https://") {
+		return errors.New("url must use https")
+	}
+	return nil
+}
+
+type validateConfig struct {
+	Level     string             `yaml:"level" validate:"oneof=debug info"`
+	Homepage  string             `yaml:"homepage" validate:"omitempty,url"`
+	Database  validateDatabase   `yaml:"database"`
+	Upstreams []validateUpstream `yaml:"upstreams"`
+}
+
+func TestLoader_ValidateAggregated(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "level: trace\nhomepage: not a url\ndatabase:\n  port: 70000\n" +
+			"upstreams:\n  - url: https://a\n  - url: http://b\n",
+	})
+
+	var conf validateConfig
+	err := New("VAL_", WithSources(FileSource(filepath.Join(tmpDir, "config.yaml")))).Load(&conf)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+
+	expected := []string{
+		"level (VAL_LEVEL): must be one of debug, info",
+		"homepage (VAL_HOMEPAGE): must be a URL",
+		"database.host (VAL_DATABASE_HOST): is required",
+		"database.port (VAL_DATABASE_PORT): must be at most 65535",
+		"upstreams[1]: url must use https",
+	}
+	if len(verr.Fields) != len(expected) {
+		t.Errorf("expected %d field errors, got %d:\n%v", len(expected), len(verr.Fields), err)
+	}
+	for _, e := range expected {
+		if !strings.Contains(err.Error(), e) {
+			t.Errorf("expected error to contain %q, got:\n%v", e, err)
+		}
+	}
+}
+
+func TestLoader_ValidateMethod(t *testing.T) {
+	t.Setenv("VALM_LEVEL", "info")
+	t.Setenv("VALM_DATABASE_HOST", "forbidden")
+	t.Setenv("VALM_DATABASE_PORT", "5432")
+
+	var conf validateConfig
+	err := New("VALM_", WithSources(EnvSource("VALM_"))).Load(&conf)
+
+	if err == nil || err.Error() != "invalid config:\n  database (VALM_DATABASE): host is forbidden" {
+		t.Errorf("expected Validate error on database, got %v", err)
+	}
+}
+
+func TestLoader_WithValidateOff(t *testing.T) {
+	var conf validateConfig
+	err := New("VALOFF_", WithSources(EnvSource("VALOFF_")), WithValidate(false)).Load(&conf)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoader_ValidateMapPointerReceiver(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "upstreams:\n  a:\n    url: https://a\n  b:\n    url: http://b\n",
+	})
+
+	var conf struct {
+		Upstreams map[string]validateUpstream `yaml:"upstreams"`
+	}
+	err := New("VALMAP_", WithSources(FileSource(filepath.Join(tmpDir, "config.yaml")))).Load(&conf)
+
+	if err == nil || err.Error() != "invalid config:\n  upstreams.b: url must use https" {
+		t.Errorf("expected Validate error on upstreams.b, got %v", err)
+	}
+}
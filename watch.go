@@ -0,0 +1,181 @@
+package mykonf
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const defaultDebounce = 100 * time.Millisecond
+
+type watchOptions struct {
+	debounce time.Duration
+	onError  func(error)
+}
+
+// WatchOption configures Watch.
+type WatchOption func(*watchOptions)
+
+// WithDebounce sets how long Watch waits for further changes before
+// reloading, 100ms by default.
+func WithDebounce(d time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.debounce = d
+	}
+}
+
+// OnError sets the handler for failed reloads and watcher errors, which
+// are logged by default.
+func OnError(fn func(error)) WatchOption {
+	return func(o *watchOptions) {
+		o.onError = fn
+	}
+}
+
+// Watch loads a T with l and calls fn(nil, conf). Then, until ctx is
+// done, it reloads through the full pipeline whenever a file read by the
+// previous load changes, including layers, includes and conf.d entries,
+// and calls fn with the previous and new config. A reload that fails keeps
+// the previous config and reports the error.
+//
+// Watch returns once the first load is done, only its error is returned.
+func Watch[T any](ctx context.Context, l *Loader, fn func(old, new *T), opts ...WatchOption) error {
+	o := watchOptions{
+		debounce: defaultDebounce,
+		onError: func(err error) {
+			log.Printf("mykonf: reload config: %v", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	cur := new(T)
+	c, err := l.load(cur)
+	if err != nil {
+		w.Close()
+		return err
+	}
+	fn(nil, cur)
+
+	fw := &fileWatcher{w: w}
+	fw.sync(c.files)
+
+	go func() {
+		defer w.Close()
+
+		timer := time.NewTimer(o.debounce)
+		timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if fw.match(ev.Name) {
+					timer.Reset(o.debounce)
+				}
+
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				o.onError(err)
+
+			case <-timer.C:
+				next := new(T)
+				c, err := l.load(next)
+				// files may have been added even if loading failed
+				fw.sync(c.files)
+				if err != nil {
+					o.onError(err)
+					continue
+				}
+
+				old := cur
+				cur = next
+				fn(old, cur)
+			}
+		}
+	}()
+	return nil
+}
+
+// fileWatcher watches the parent dirs of files, so that atomic saves by
+// editors and files created later are seen as well. It also keeps the
+// resolved target of each file, since Kubernetes ConfigMap and Secret
+// volumes update by swapping a ..data symlink, which no event names the
+// files for.
+type fileWatcher struct {
+	w       *fsnotify.Watcher
+	files   map[string]bool
+	dirs    map[string]bool
+	targets map[string]string
+}
+
+// sync makes the watcher follow files, which may include directories.
+func (fw *fileWatcher) sync(files []string) {
+	fw.files = make(map[string]bool)
+	fw.targets = make(map[string]string)
+	dirs := make(map[string]bool)
+	for _, f := range files {
+		fw.files[f] = true
+		fw.targets[f], _ = filepath.EvalSymlinks(f)
+		dirs[filepath.Dir(f)] = true
+		// a watched directory reports changes of its own entries
+		if fi, err := os.Stat(f); err == nil && fi.IsDir() {
+			dirs[f] = true
+		}
+	}
+
+	for d := range fw.dirs {
+		if !dirs[d] {
+			fw.w.Remove(d)
+		}
+	}
+	fw.dirs = make(map[string]bool)
+	for d := range dirs {
+		// missing dirs and plain files fail here, which is fine
+		if fw.w.Add(d) == nil {
+			fw.dirs[d] = true
+		}
+	}
+}
+
+// match reports whether a change of name affects the config.
+func (fw *fileWatcher) match(name string) bool {
+	name = filepath.Clean(name)
+	if fw.files[name] || fw.files[filepath.Dir(name)] {
+		return true
+	}
+	return fw.retargeted(filepath.Dir(name))
+}
+
+// retargeted reports whether a file in dir now resolves to another target,
+// and records the new targets.
+func (fw *fileWatcher) retargeted(dir string) bool {
+	changed := false
+	for f, target := range fw.targets {
+		if filepath.Dir(f) != dir {
+			continue
+		}
+		if t, _ := filepath.EvalSymlinks(f); t != target {
+			fw.targets[f] = t
+			changed = true
+		}
+	}
+	return changed
+}
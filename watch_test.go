@@ -0,0 +1,159 @@
+package mykonf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type watchConfig struct {
+	Name     string `yaml:"name"`
+	Database struct {
+		Host string `yaml:"host"`
+	} `yaml:"database"`
+}
+
+func TestWatch_Reload(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"config.yaml": "name: first\ndatabase: !include db.yaml\n",
+		"db.yaml":     "host: host1\n",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan [2]*watchConfig, 4)
+	errs := make(chan error, 4)
+	loader := New("TEST_", WithSources(FileSource(filepath.Join(tmpDir, "config.yaml"))))
+
+	err := Watch(ctx, loader, func(old, new *watchConfig) {
+		changes <- [2]*watchConfig{old, new}
+	}, WithDebounce(50*time.Millisecond), OnError(func(err error) {
+		errs <- err
+	}))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first := <-changes
+	if first[0] != nil || first[1].Name != "first" {
+		t.Fatalf("expected initial call with nil old and Name='first', got %v %+v", first[0], first[1])
+	}
+
+	writeFiles(t, tmpDir, map[string]string{"db.yaml": "host: host2\n"})
+
+	select {
+	case c := <-changes:
+		if c[0].Database.Host != "host1" || c[1].Database.Host != "host2" {
+			t.Errorf("expected host1 -> host2, got %q -> %q", c[0].Database.Host, c[1].Database.Host)
+		}
+	case err := <-errs:
+		t.Fatalf("unexpected reload error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload of included file")
+	}
+
+	writeFiles(t, tmpDir, map[string]string{"config.yaml": "name: [broken\n"})
+
+	select {
+	case c := <-changes:
+		t.Fatalf("expected failed reload to keep the previous config, got %+v", c[1])
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload error")
+	}
+
+	writeFiles(t, tmpDir, map[string]string{"config.yaml": "name: second\n"})
+
+	select {
+	case c := <-changes:
+		if c[0].Name != "first" || c[1].Name != "second" {
+			t.Errorf("expected first -> second, got %q -> %q", c[0].Name, c[1].Name)
+		}
+	case err := <-errs:
+		t.Fatalf("unexpected reload error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+}
+
+func TestWatch_InitialError(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "config.yaml")
+
+	if err := os.WriteFile(tmpFile, []byte("name: [broken\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	called := false
+	err := Watch(context.Background(), New("TEST_", WithSources(FileSource(tmpFile))),
+		func(old, new *watchConfig) { called = true })
+
+	if err == nil {
+		t.Fatal("expected error for invalid initial config")
+	}
+
+	if called {
+		t.Error("callback should not be called when the first load fails")
+	}
+}
+
+// TestWatch_SymlinkSwap updates the config the way Kubernetes updates
+// ConfigMap volumes: config.yaml links to ..data/config.yaml and ..data is
+// swapped to a new directory, so no event names config.yaml.
+func TestWatch_SymlinkSwap(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFiles(t, tmpDir, map[string]string{
+		"..v1/config.yaml": "name: first\n",
+		"..v2/config.yaml": "name: second\n",
+	})
+	for old, link := range map[string]string{
+		"..v1":               "..data",
+		"..data/config.yaml": "config.yaml",
+	} {
+		if err := os.Symlink(old, filepath.Join(tmpDir, link)); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan [2]*watchConfig, 4)
+	errs := make(chan error, 4)
+	loader := New("TEST_", WithSources(FileSource(filepath.Join(tmpDir, "config.yaml"))))
+
+	err := Watch(ctx, loader, func(old, new *watchConfig) {
+		changes <- [2]*watchConfig{old, new}
+	}, WithDebounce(50*time.Millisecond), OnError(func(err error) {
+		errs <- err
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first := <-changes; first[1].Name != "first" {
+		t.Fatalf("expected Name='first', got %q", first[1].Name)
+	}
+
+	if err := os.Symlink("..v2", filepath.Join(tmpDir, "..data_tmp")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if err := os.Rename(filepath.Join(tmpDir, "..data_tmp"), filepath.Join(tmpDir, "..data")); err != nil {
+		t.Fatalf("failed to swap symlink: %v", err)
+	}
+
+	select {
+	case c := <-changes:
+		if c[0].Name != "first" || c[1].Name != "second" {
+			t.Errorf("expected first -> second, got %q -> %q", c[0].Name, c[1].Name)
+		}
+	case err := <-errs:
+		t.Fatalf("unexpected reload error: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload after the symlink swap")
+	}
+}